@@ -0,0 +1,123 @@
+package gnupg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	gpgAgentConfFile = "gpg-agent.conf"
+	gpgPresetBin     = "gpg-preset-passphrase"
+)
+
+// GetKeygrips returns the keygrips of the primary key and all subkeys of the imported key.
+// It runs the `gpg --list-secret-keys --with-keygrip` command and parses the colon
+// delimited output.
+func (c *Client) GetKeygrips(ctx context.Context) ([]string, error) {
+	args := []string{
+		"--batch",
+		"--with-colons",
+		"--with-keygrip",
+		"--list-secret-keys",
+		c.Key.ID,
+	}
+
+	cmd, err := c.newCommand(ctx, c.gpgBin, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := cmd.output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGetKeygripsFailed, err)
+	}
+
+	var keygrips []string
+
+	lines := strings.Split(strings.ReplaceAll(string(out), "\r", ""), "\n")
+	for _, line := range lines {
+		fields := strings.Split(line, ":")
+		if len(fields) > 9 && fields[0] == "grp" && fields[9] != "" {
+			keygrips = append(keygrips, fields[9])
+		}
+	}
+
+	if len(keygrips) == 0 {
+		return nil, fmt.Errorf("%w: no keygrip found for key %s", ErrGetKeygripsFailed, c.Key.ID)
+	}
+
+	return keygrips, nil
+}
+
+// ConfigureAgent writes a gpg-agent.conf to the home directory that allows to preset
+// passphrases and sets the given cache TTL, and restarts the gpg-agent to apply it. The
+// TTL does not apply to preset passphrases, which stay cached until the agent is stopped
+// by Cleanup unless the home directory is persisted.
+func (c *Client) ConfigureAgent(ctx context.Context, ttl time.Duration) error {
+	seconds := int(ttl.Seconds())
+	conf := fmt.Sprintf(
+		"allow-preset-passphrase\ndefault-cache-ttl %d\nmax-cache-ttl %d\n",
+		seconds, seconds,
+	)
+
+	if err := os.WriteFile(filepath.Join(c.Homedir, gpgAgentConfFile), []byte(conf), strictFilePerm); err != nil {
+		return fmt.Errorf("failed to write gpg-agent.conf: %w", err)
+	}
+
+	if err := c.runGPGConf(ctx, "--kill", "gpg-agent"); err != nil {
+		return fmt.Errorf("failed to stop gpg-agent: %w", err)
+	}
+
+	if err := c.runGPGConf(ctx, "--launch", "gpg-agent"); err != nil {
+		return fmt.Errorf("failed to start gpg-agent: %w", err)
+	}
+
+	c.agent = true
+
+	return nil
+}
+
+// PresetPassphrase stores the passphrase of the key in the cache of the gpg-agent for all
+// keygrips of the key. Afterwards, the passphrase is no longer passed to gpg on signing,
+// and gpg.conf written by WriteGPGProgram does not reference it.
+func (c *Client) PresetPassphrase(ctx context.Context) error {
+	keygrips, err := c.GetKeygrips(ctx)
+	if err != nil {
+		return err
+	}
+
+	bin := c.gpgPresetBin
+	if bin == "" {
+		bin = filepath.Join(c.Dirs.Libexec, gpgPresetBin)
+	}
+
+	for _, keygrip := range keygrips {
+		cmd, err := c.newCommand(ctx, bin, "--preset", keygrip)
+		if err != nil {
+			return err
+		}
+
+		cmd.Stdin = strings.NewReader(c.Key.Passphrase)
+
+		if err := cmd.run(); err != nil {
+			return fmt.Errorf("failed to preset passphrase for keygrip %s: %w", keygrip, err)
+		}
+	}
+
+	c.preset = true
+
+	return nil
+}
+
+func (c *Client) runGPGConf(ctx context.Context, args ...string) error {
+	cmd, err := c.newCommand(ctx, c.gpgconfBin, args...)
+	if err != nil {
+		return err
+	}
+
+	return cmd.run()
+}
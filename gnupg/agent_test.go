@@ -0,0 +1,117 @@
+package gnupg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetKeygrips(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "success",
+			env:  []string{"GO_TEST_MODE=gpg --list-secret-keys"},
+			want: []string{
+				"9A8F1A2BF0C34E1D2B8E0A5E1B7F7C9D0E4A3B21",
+				"1F2E3D4C5B6A79880F1E2D3C4B5A69788F9E0D1C",
+			},
+		},
+		{
+			name:    "no keygrips",
+			env:     []string{"GO_TEST_MODE=pass"},
+			wantErr: ErrGetKeygripsFailed,
+		},
+		{
+			name:    "fail",
+			env:     []string{"GO_TEST_MODE=fail"},
+			wantErr: ErrGetKeygripsFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				gpgBin: os.Args[0],
+				Env:    tt.env,
+				Key:    Key{ID: testKeyID},
+			}
+
+			got, err := c.GetKeygrips(t.Context())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_ConfigureAgent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &Client{
+		gpgconfBin:  os.Args[0],
+		traceWriter: buf,
+		Homedir:     t.TempDir(),
+		Env:         []string{"GO_TEST_MODE=pass"},
+	}
+
+	require.NoError(t, c.ConfigureAgent(t.Context(), 90*time.Minute))
+
+	conf, err := os.ReadFile(filepath.Join(c.Homedir, "gpg-agent.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "allow-preset-passphrase\ndefault-cache-ttl 5400\nmax-cache-ttl 5400\n", string(conf))
+	assert.Contains(t, buf.String(), "gnupg.test --kill gpg-agent")
+	assert.Contains(t, buf.String(), "gnupg.test --launch gpg-agent")
+
+	buf.Reset()
+	require.NoError(t, c.Cleanup(t.Context()))
+	assert.Contains(t, buf.String(), "gnupg.test --kill gpg-agent")
+	assert.NoDirExists(t, c.Homedir)
+}
+
+func TestClient_PresetPassphrase(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &Client{
+		gpgBin:       os.Args[0],
+		gpgPresetBin: os.Args[0],
+		traceWriter:  buf,
+		Homedir:      t.TempDir(),
+		Env:          []string{"GO_TEST_MODE=gpg --list-secret-keys"},
+		Key: Key{
+			ID:          testKeyID,
+			Fingerprint: testKeyFingerprint,
+			Passphrase:  "secret",
+		},
+	}
+
+	require.NoError(t, c.PresetPassphrase(t.Context()))
+	assert.Contains(t, buf.String(), "gnupg.test --preset 9A8F1A2BF0C34E1D2B8E0A5E1B7F7C9D0E4A3B21")
+	assert.Contains(t, buf.String(), "gnupg.test --preset 1F2E3D4C5B6A79880F1E2D3C4B5A69788F9E0D1C")
+
+	// The passphrase is neither passed on signing nor written to the homedir
+	buf.Reset()
+	_, err := c.SignFileTo(t.Context(), false, true, false, "/path/to/file", "/path/to/file.sig")
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "--passphrase-fd")
+
+	wrapper, err := c.WriteGPGProgram()
+	require.NoError(t, err)
+	assert.Empty(t, c.passphraseFile)
+
+	script, err := os.ReadFile(wrapper)
+	require.NoError(t, err)
+	assert.NotContains(t, string(script), "--passphrase-file")
+}
@@ -0,0 +1,85 @@
+package gnupg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SignRequest describes a file to sign with SignFiles.
+type SignRequest struct {
+	Path       string
+	Output     string
+	Armor      bool
+	DetachSign bool
+	ClearSign  bool
+}
+
+// SignResult is the outcome of a SignRequest.
+type SignResult struct {
+	SignRequest
+	Signature SignatureCreated
+	Err       error
+}
+
+// SignFiles signs all given files with the configured key. With the native backend, the
+// key is unlocked once and the files are signed in-process, which avoids the process
+// start and the passphrase handshake of gpg for every file. The signatures are written
+// in the same format gpg would use for the given flags. With the gpg backend, every file
+// is still signed by its own gpg call like with SignFileTo, so batching gains nothing
+// apart from stopping early on a wrong passphrase. Signing stops if the context is
+// canceled.
+// It returns a result for every request, the error is only set if the key could not
+// be unlocked.
+func (c *Client) SignFiles(ctx context.Context, requests []SignRequest) ([]SignResult, error) {
+	if c.Backend != BackendNative {
+		return c.signFilesGPG(ctx, requests)
+	}
+
+	key, err := c.unlockSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SignResult, len(requests))
+
+	for i, req := range requests {
+		results[i].SignRequest = req
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = fmt.Errorf("failed to sign file: %w", err)
+
+			continue
+		}
+
+		results[i].Signature, results[i].Err = key.sign(ctx, req)
+	}
+
+	return results, nil
+}
+
+// signFilesGPG signs the given files with a gpg call per file. A wrong or missing
+// passphrase fails for every file, so it is returned as unlock error after the first
+// file and the remaining files are not signed.
+func (c *Client) signFilesGPG(ctx context.Context, requests []SignRequest) ([]SignResult, error) {
+	results := make([]SignResult, len(requests))
+
+	for i, req := range requests {
+		results[i].SignRequest = req
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = fmt.Errorf("failed to sign file: %w", err)
+
+			continue
+		}
+
+		sig, err := c.SignFileTo(ctx, req.Armor, req.DetachSign, req.ClearSign, req.Path, req.Output)
+		if errors.Is(err, ErrBadPassphrase) || errors.Is(err, ErrMissingPassphrase) {
+			return nil, fmt.Errorf("%w: %w", ErrUnlockKeyFailed, err)
+		}
+
+		results[i].Signature, results[i].Err = sig, err
+	}
+
+	return results, nil
+}
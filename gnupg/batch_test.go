@@ -0,0 +1,293 @@
+package gnupg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SignFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		cancel     bool
+		missing    bool
+		wantErr    error
+		wantFail   bool
+	}{
+		{
+			name:       "sign files",
+			passphrase: testPassphrase,
+		},
+		{
+			name:       "missing file",
+			passphrase: testPassphrase,
+			missing:    true,
+		},
+		{
+			name:       "canceled context",
+			passphrase: testPassphrase,
+			cancel:     true,
+			wantFail:   true,
+		},
+		{
+			name:       "invalid passphrase",
+			passphrase: "invalid",
+			wantErr:    ErrUnlockKeyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			requests := []SignRequest{
+				{Path: filepath.Join(tmpDir, "a.txt"), DetachSign: true},
+				{Path: filepath.Join(tmpDir, "b.txt"), DetachSign: true, Armor: true},
+				{Path: filepath.Join(tmpDir, "c.txt"), ClearSign: true},
+			}
+
+			for i, req := range requests {
+				if !tt.missing || i != 1 {
+					require.NoError(t, os.WriteFile(req.Path, []byte(req.Path), 0o600))
+				}
+
+				requests[i].Output = SignaturePath(req.Armor, req.DetachSign, req.ClearSign, req.Path)
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			c := &Client{
+				Backend: BackendNative,
+				Key: Key{
+					Content:    testPrivateKey,
+					Passphrase: tt.passphrase,
+				},
+			}
+
+			results, err := c.SignFiles(ctx, requests)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, results, len(requests))
+
+			pubKey, err := crypto.NewKeyFromArmored(testPublicKey)
+			require.NoError(t, err)
+
+			verifier, err := crypto.PGP().Verify().VerificationKey(pubKey).New()
+			require.NoError(t, err)
+
+			for i, res := range results {
+				assert.Equal(t, requests[i], res.SignRequest)
+
+				if tt.wantFail || (tt.missing && i == 1) {
+					assert.Error(t, res.Err)
+					assert.NoFileExists(t, res.Output)
+
+					continue
+				}
+
+				assert.NoError(t, res.Err)
+
+				sig, err := os.ReadFile(res.Output)
+				require.NoError(t, err)
+
+				if res.ClearSign {
+					vres, err := verifier.VerifyCleartext(sig)
+					require.NoError(t, err)
+					assert.NoError(t, vres.SignatureError())
+
+					continue
+				}
+
+				encoding := crypto.Bytes
+				if res.Armor {
+					encoding = crypto.Armor
+				}
+
+				vres, err := verifier.VerifyDetached([]byte(res.Path), sig, encoding)
+				require.NoError(t, err)
+				assert.NoError(t, vres.SignatureError())
+			}
+		})
+	}
+}
+
+func TestClient_SignFilesGPG(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		status  string
+		cancel  bool
+		wantErr error
+		want    []error
+	}{
+		{
+			name:   "sign files",
+			mode:   "status ok",
+			status: "SIG_CREATED D 1 8 00 1710151543 " + testKeyFingerprint,
+			want:   []error{nil, nil},
+		},
+		{
+			name:   "gpg failure",
+			mode:   "status",
+			status: "NO_SECKEY " + testKeyFingerprint,
+			want:   []error{ErrNoSecretKey, ErrNoSecretKey},
+		},
+		{
+			name:   "canceled context",
+			mode:   "status ok",
+			cancel: true,
+			want:   []error{context.Canceled, context.Canceled},
+		},
+		{
+			name:    "bad passphrase",
+			mode:    "status",
+			status:  "BAD_PASSPHRASE " + testKeyFingerprint,
+			wantErr: ErrUnlockKeyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []SignRequest{
+				{Path: "/path/to/a.txt", Output: "/path/to/a.txt.sig", DetachSign: true},
+				{Path: "/path/to/b.txt", Output: "/path/to/b.txt.asc", DetachSign: true, Armor: true},
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			c := &Client{
+				Backend: BackendGPG,
+				gpgBin:  os.Args[0],
+				Env:     []string{"GO_TEST_MODE=" + tt.mode, "GO_TEST_STATUS=" + statusLines(tt.status)},
+				Key:     Key{Fingerprint: testKeyFingerprint, Passphrase: testPassphrase},
+			}
+
+			results, err := c.SignFiles(ctx, requests)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrBadPassphrase)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, results, len(requests))
+
+			for i, res := range results {
+				assert.Equal(t, requests[i], res.SignRequest)
+
+				if tt.want[i] != nil {
+					assert.ErrorIs(t, res.Err, tt.want[i])
+
+					continue
+				}
+
+				assert.NoError(t, res.Err)
+				assert.Equal(t, testKeyFingerprint, res.Signature.Fingerprint)
+			}
+		})
+	}
+}
+
+func BenchmarkClient_SignFiles(b *testing.B) {
+	const files = 20
+
+	tmpDir := b.TempDir()
+	requests := make([]SignRequest, files)
+
+	for i := range requests {
+		path := filepath.Join(tmpDir, fmt.Sprintf("file-%d.txt", i))
+		require.NoError(b, os.WriteFile(path, []byte(path), 0o600))
+
+		requests[i] = SignRequest{Path: path, Output: path + ".sig", DetachSign: true}
+	}
+
+	signEach := func(b *testing.B, c *Client) {
+		b.Helper()
+
+		for range b.N {
+			for _, req := range requests {
+				_, err := c.SignFileTo(b.Context(), req.Armor, req.DetachSign, req.ClearSign, req.Path, req.Output)
+				require.NoError(b, err)
+			}
+		}
+	}
+
+	b.Run("gpg per file", func(b *testing.B) {
+		if _, err := exec.LookPath(gpgBin); err != nil {
+			b.Skip("gpg binary not found")
+		}
+
+		// Keep the default homedir of the client out of the real home directory
+		b.Setenv("HOME", b.TempDir())
+
+		c, err := New(testPrivateKey, testPassphrase)
+		require.NoError(b, err)
+
+		c = c.WithOutput(io.Discard)
+		require.NoError(b, c.ReadPrivateKey())
+		require.NoError(b, c.ImportKey(b.Context()))
+
+		b.Cleanup(func() {
+			cmd := exec.Command(gpgconfBin, "--kill", "gpg-agent")
+			cmd.Env = append(os.Environ(), c.Env...)
+			_ = cmd.Run()
+		})
+
+		b.ResetTimer()
+		signEach(b, c)
+	})
+
+	b.Run("native per file", func(b *testing.B) {
+		signEach(b, &Client{
+			Backend: BackendNative,
+			Key:     Key{Content: testPrivateKey, Passphrase: testPassphrase},
+		})
+	})
+
+	b.Run("native per file unlocked once", func(b *testing.B) {
+		signEach(b, &Client{
+			Backend:  BackendNative,
+			Key:      Key{Content: testPrivateKey, Passphrase: testPassphrase},
+			unlocked: newUnlockedKeys(),
+		})
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		c := &Client{
+			Backend: BackendNative,
+			Key:     Key{Content: testPrivateKey, Passphrase: testPassphrase},
+		}
+
+		for range b.N {
+			results, err := c.SignFiles(b.Context(), requests)
+			require.NoError(b, err)
+
+			for _, res := range results {
+				require.NoError(b, res.Err)
+			}
+		}
+	})
+}
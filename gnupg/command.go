@@ -0,0 +1,158 @@
+package gnupg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/execabs"
+
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+var (
+	ErrCommandTimeout  = errors.New("gpg command timed out")
+	ErrCommandCanceled = errors.New("gpg command canceled")
+)
+
+// commandWaitDelay is the time to wait for the output of a killed command, e.g. if
+// a started gpg-agent keeps the error output open.
+const commandWaitDelay = 5 * time.Second
+
+// command is an external command bound to a context. The error output is recorded
+// to describe the command if it is killed.
+type command struct {
+	*plugin_exec.Cmd
+
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	stderr  bytes.Buffer
+}
+
+// newCommand returns a command for the given binary with the environment, trace and
+// error output of the client. The command is killed if the context is canceled or
+// takes longer than the Timeout of the client.
+func (c *Client) newCommand(ctx context.Context, bin string, args ...string) (*command, error) {
+	absBin, err := execabs.LookPath(bin)
+	if err != nil {
+		return nil, fmt.Errorf("could not find executable %q: %w", bin, err)
+	}
+
+	cmd := &command{
+		parent:  ctx,
+		ctx:     ctx,
+		cancel:  func() {},
+		timeout: c.Timeout,
+	}
+
+	if c.Timeout > 0 {
+		cmd.ctx, cmd.cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	cmd.Cmd = plugin_exec.CommandContext(cmd.ctx, absBin, args...)
+	cmd.Env = append(cmd.Env, c.Env...)
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Stderr = &cmd.stderr
+
+	if c.stderr != nil {
+		cmd.Stderr = io.MultiWriter(c.stderr, &cmd.stderr)
+	}
+
+	if c.traceWriter != nil {
+		cmd.TraceWriter = c.traceWriter
+	}
+
+	return cmd, nil
+}
+
+// run runs the command and waits for it to complete.
+func (cmd *command) run() error {
+	defer cmd.cancel()
+
+	return cmd.wrapError(cmd.Run())
+}
+
+// runStatus runs gpg with a pipe as extra file and returns the parsed status lines,
+// which requires the `--status-fd` option with statusFD in the arguments. If gpg fails,
+// the typed errors of the status lines are returned if there are any.
+func (cmd *command) runStatus() ([]Status, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status pipe: %w", err)
+	}
+	defer r.Close()
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+
+	type result struct {
+		statuses []Status
+		err      error
+	}
+
+	parsed := make(chan result, 1)
+
+	go func() {
+		statuses, err := ParseStatus(r)
+		parsed <- result{statuses: statuses, err: err}
+	}()
+
+	runErr := cmd.run()
+
+	// Close the write end of the parent to stop the status reader
+	w.Close()
+
+	res := <-parsed
+
+	if runErr != nil {
+		if cmd.ctx.Err() == nil {
+			if err := statusError(res.statuses); err != nil {
+				return res.statuses, err
+			}
+		}
+
+		return res.statuses, runErr
+	}
+
+	return res.statuses, res.err
+}
+
+// output runs the command and returns its standard output.
+func (cmd *command) output() ([]byte, error) {
+	defer cmd.cancel()
+
+	out, err := cmd.Output()
+
+	return out, cmd.wrapError(err)
+}
+
+// wrapError returns a timeout or cancellation error with the command line and the
+// recorded error output if the command was killed. Other errors are returned as is.
+func (cmd *command) wrapError(err error) error {
+	if err == nil || cmd.ctx.Err() == nil {
+		return err
+	}
+
+	if cause := cmd.parent.Err(); cause != nil {
+		return fmt.Errorf("%w: %s: %w", ErrCommandCanceled, cmd.describe(), cause)
+	}
+
+	return fmt.Errorf("%w after %s: %s", ErrCommandTimeout, cmd.timeout, cmd.describe())
+}
+
+// describe returns the command line and the recorded error output of the command.
+func (cmd *command) describe() string {
+	desc := strings.Join(cmd.Args, " ")
+
+	if stderr := strings.TrimSpace(cmd.stderr.String()); stderr != "" {
+		desc += ": stderr: " + strings.ReplaceAll(stderr, "\n", "; ")
+	}
+
+	return desc
+}
@@ -0,0 +1,88 @@
+package gnupg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CommandTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		timeout  time.Duration
+		cancel   bool
+		wantErr  error
+		wantDesc []string
+	}{
+		{
+			name:    "success",
+			mode:    "pass",
+			timeout: time.Minute,
+		},
+		{
+			name:     "timeout",
+			mode:     "hang",
+			timeout:  500 * time.Millisecond,
+			wantErr:  ErrCommandTimeout,
+			wantDesc: []string{"after 500ms", "--batch --status-fd 3 --import -", "stderr: gpg: waiting for lock"},
+		},
+		{
+			name:     "canceled",
+			mode:     "hang",
+			cancel:   true,
+			wantErr:  context.Canceled,
+			wantDesc: []string{"gpg command canceled", "--batch --status-fd 3 --import -"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			if tt.cancel {
+				cancel()
+			}
+
+			c := &Client{
+				gpgBin:  os.Args[0],
+				Env:     []string{"GO_TEST_MODE=" + tt.mode},
+				Timeout: tt.timeout,
+			}
+
+			err := c.ImportKey(ctx)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			for _, desc := range tt.wantDesc {
+				assert.ErrorContains(t, err, desc)
+			}
+		})
+	}
+}
+
+func TestClient_SignFileNativeCanceled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	c := &Client{
+		Backend: BackendNative,
+		Key:     Key{Content: testPrivateKey, Passphrase: testPassphrase},
+	}
+
+	assert.ErrorIs(t, c.SignFile(ctx, false, true, false, path), context.Canceled)
+}
@@ -1,6 +1,7 @@
 package gnupg
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,10 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/sys/execabs"
-
-	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
 )
 
 var (
@@ -25,20 +25,37 @@ const (
 	gpgBin     = "/usr/bin/gpg"
 	gpgconfBin = "/usr/bin/gpgconf"
 
+	BackendGPG    = "gpg"
+	BackendNative = "native"
+
 	strictDirPerm  = 0o700
 	strictFilePerm = 0o600
 )
 
 type Client struct {
-	gpgBin      string
-	gpgconfBin  string
-	traceWriter io.Writer
-
+	gpgBin         string
+	gpgconfBin     string
+	gpgPresetBin   string
+	traceWriter    io.Writer
+	stderr         io.Writer
+	keyring        *crypto.KeyRing
+	persist        bool
+	passphraseFile string
+	agent          bool
+	preset         bool
+	unlocked       *unlockedKeys
+
+	Backend string
 	Homedir string
 	Env     []string
 	Key     Key
 	Version Version
 	Dirs    Dirs
+
+	// SignatureTime pins the creation time of all signatures if set.
+	SignatureTime time.Time
+	// Timeout limits the run time of every external command if set.
+	Timeout time.Duration
 }
 
 type Key struct {
@@ -49,6 +66,9 @@ type Key struct {
 	Fingerprint  string
 	Identity     string
 	CreationTime time.Time
+	UserIDs      []UserID
+	Subkeys      []Subkey
+	Health       KeyHealth
 }
 
 type Version struct {
@@ -72,6 +92,9 @@ func New(key, passphrase string) (*Client, error) {
 		gpgBin:      gpgBin,
 		gpgconfBin:  gpgconfBin,
 		traceWriter: os.Stdout,
+		stderr:      os.Stderr,
+		unlocked:    newUnlockedKeys(),
+		Backend:     BackendGPG,
 		Key: Key{
 			Content:    key,
 			Passphrase: passphrase,
@@ -96,6 +119,30 @@ func New(key, passphrase string) (*Client, error) {
 	return client, nil
 }
 
+// WithOutput returns a copy of the client that writes the command traces and the
+// error output of gpg to the given writer. The copy shares the homedir and the
+// gpg-agent with the client, but uses its own key, which allows to sign files
+// concurrently with multiple copies. Private keys unlocked by the native backend are
+// shared with the copy.
+func (c *Client) WithOutput(w io.Writer) *Client {
+	client := *c
+	client.traceWriter = w
+	client.stderr = w
+
+	return &client
+}
+
+// Logger returns the logger attached to the given context, or the global logger if the
+// context has none. It allows to redirect the log events of a single operation, e.g. to
+// buffer them while files are signed concurrently.
+func Logger(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+
+	return &log.Logger
+}
+
 // SetHomedir sets the home directory for the GnuPG client. It creates the directory
 // if it does not already exist, and updates the GNUPGHOME environment variable
 // for the client.
@@ -114,16 +161,13 @@ func (c *Client) SetHomedir(path string) error {
 // GetDirs retrieves the directories used by the GnuPG binary.
 // It runs the `gpgconf --list-dirs` command and parses the output
 // to populate the Dirs field of the Client struct.
-func (c *Client) GetDirs() error {
-	absBin, err := execabs.LookPath(c.gpgconfBin)
+func (c *Client) GetDirs(ctx context.Context) error {
+	cmd, err := c.newCommand(ctx, c.gpgconfBin, "--list-dirs")
 	if err != nil {
-		return fmt.Errorf("could not find executable %q: %w", c.gpgconfBin, err)
+		return err
 	}
 
-	cmd := plugin_exec.Command(absBin, "--list-dirs")
-	cmd.Env = append(cmd.Env, c.Env...)
-
-	out, err := cmd.Output()
+	out, err := cmd.output()
 	if err != nil {
 		return err
 	}
@@ -165,18 +209,15 @@ func (c *Client) GetDirs() error {
 // GetVersion returns the version information for the GnuPG binary used by the Client.
 // It parses the output of the `gpg --version` command to extract the version
 // numbers for GnuPG and libgcrypt.
-func (c *Client) GetVersion() (*Version, error) {
+func (c *Client) GetVersion(ctx context.Context) (*Version, error) {
 	version := &Version{}
 
-	absBin, err := execabs.LookPath(c.gpgBin)
+	cmd, err := c.newCommand(ctx, c.gpgBin, "--version")
 	if err != nil {
-		return version, fmt.Errorf("could not find executable %q: %w", c.gpgconfBin, err)
+		return version, err
 	}
 
-	cmd := plugin_exec.Command(absBin, "--version")
-	cmd.Env = append(cmd.Env, c.Env...)
-
-	out, err := cmd.CombinedOutput()
+	out, err := cmd.output()
 	if err != nil {
 		return version, err
 	}
@@ -196,9 +237,31 @@ func (c *Client) GetVersion() (*Version, error) {
 	return version, nil
 }
 
-// Cleanup removes the GnuPG home directory if it was created by the Client.
-func (c *Client) Cleanup() error {
-	if c.Homedir != "" {
+// Cleanup removes the GnuPG home directory if it was created by the Client and stops
+// the gpg-agent started by ConfigureAgent. The passphrase file written by
+// WriteGPGProgram and the keys unlocked by the native backend are always removed.
+// A home directory persisted with PersistHomedir is kept together with its agent.
+// The agent is stopped even if the context is already canceled.
+func (c *Client) Cleanup(ctx context.Context) error {
+	if c.agent && !c.persist {
+		if err := c.runGPGConf(context.WithoutCancel(ctx), "--kill", "gpg-agent"); err != nil {
+			log.Warn().Msgf("failed to stop gpg-agent: %s", err)
+		}
+	}
+
+	if c.unlocked != nil {
+		c.unlocked.clear()
+	}
+
+	if c.passphraseFile != "" {
+		if err := os.Remove(c.passphraseFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove passphrase file: %w", err)
+		}
+
+		c.passphraseFile = ""
+	}
+
+	if c.Homedir != "" && !c.persist {
 		if err := os.RemoveAll(c.Homedir); err != nil {
 			return fmt.Errorf("failed to cleanup homedir %s: %w", c.Homedir, err)
 		}
@@ -1,10 +1,13 @@
 package gnupg
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,6 +20,22 @@ func TestMain(m *testing.M) {
 
 	case "pass":
 
+	case "args":
+		fmt.Println(strings.Join(os.Args[1:], " "))
+
+	case "status":
+		status := os.NewFile(3, "status")
+		fmt.Fprintln(status, os.Getenv("GO_TEST_STATUS"))
+		os.Exit(2)
+
+	case "status ok":
+		status := os.NewFile(3, "status")
+		fmt.Fprintln(status, os.Getenv("GO_TEST_STATUS"))
+
+	case "hang":
+		fmt.Fprintln(os.Stderr, "gpg: waiting for lock")
+		time.Sleep(time.Minute)
+
 	case "gpgconf --list-dirs":
 		fmt.Println(`sysconfdir:/etc/gnupg
 libexecdir:/usr/libexec
@@ -29,6 +48,15 @@ homedir:/home/user/.gnupg`)
 libgcrypt 1.10.2
 Copyright (C) 2024 g10 Code GmbH`)
 
+	case "gpg --list-secret-keys":
+		fmt.Println(`sec:u:255:22:E0F9F7BA7C6E1C3A:1710202283:::u:::scSC:::+:::ed25519:::0:
+fpr:::::::::5C3BDE1F2B4A0C6E3F6FE0C1E0F9F7BA7C6E1C3A:
+grp:::::::::9A8F1A2BF0C34E1D2B8E0A5E1B7F7C9D0E4A3B21:
+uid:u::::1710202283::2CDE4B8F3A3C1A6E0F3C9E1B5E4A3C2B1A0F9E8D::John Doe <john.doe@example.com>::::::::::0:
+ssb:u:255:18:6B1E3D2C4A5F6E7D:1710202283::::::e:::+:::cv25519::
+fpr:::::::::0A1B2C3D4E5F60718293A4B5C6D7E8F96B1E3D2C:
+grp:::::::::1F2E3D4C5B6A79880F1E2D3C4B5A69788F9E0D1C:`)
+
 	default:
 		fmt.Println("Unknown GO_TEST_MODE")
 		os.Exit(1)
@@ -104,7 +132,7 @@ func TestClient_GetDirs(t *testing.T) {
 				Env:        tt.env,
 			}
 
-			err := c.GetDirs()
+			err := c.GetDirs(t.Context())
 			if tt.wantErr {
 				assert.Error(t, err)
 
@@ -154,7 +182,7 @@ func TestClient_GetVersion(t *testing.T) {
 				Env:    tt.env,
 			}
 
-			got, err := c.GetVersion()
+			got, err := c.GetVersion(t.Context())
 			if tt.wantErr != nil {
 				assert.Error(t, err)
 
@@ -166,3 +194,24 @@ func TestClient_GetVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_WithOutput(t *testing.T) {
+	var output bytes.Buffer
+
+	c := &Client{
+		gpgBin:      os.Args[0],
+		traceWriter: os.Stdout,
+		stderr:      os.Stderr,
+		Env:         []string{"GO_TEST_MODE=pass"},
+		Key:         Key{ID: "E0F9F7BA7C6E1C3A"},
+	}
+
+	fork := c.WithOutput(&output)
+	fork.UseKey(Key{ID: "6B1E3D2C4A5F6E7D"})
+
+	assert.NoError(t, fork.SetTrustLevel(t.Context(), "full"))
+	assert.Contains(t, output.String(), "--edit-key 6B1E3D2C4A5F6E7D")
+	assert.Equal(t, "E0F9F7BA7C6E1C3A", c.Key.ID)
+	assert.Equal(t, os.Stdout, c.traceWriter)
+	assert.Equal(t, os.Stderr, c.stderr)
+}
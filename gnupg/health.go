@@ -0,0 +1,189 @@
+package gnupg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+var (
+	ErrKeyExpired         = errors.New("key is expired")
+	ErrKeyRevoked         = errors.New("key is revoked")
+	ErrKeyNotYetValid     = errors.New("key was created after the signature time")
+	ErrKeyExpiresSoon     = errors.New("key expires soon")
+	ErrKeyAlgorithmPolicy = errors.New("key algorithm not allowed by policy")
+)
+
+// KeyPolicy defines the requirements the signing key has to meet before it is used.
+type KeyPolicy struct {
+	// ExpiryWarn is the window before the key expiry in which a warning is reported.
+	ExpiryWarn time.Duration
+	// ExpiryFail is the window before the key expiry in which the check fails.
+	ExpiryFail time.Duration
+	// MinRSABits is the minimum key size of RSA keys. Zero disables the check.
+	MinRSABits int
+	// Algorithms is the list of allowed algorithms, e.g. `rsa4096` or `ed25519`.
+	// A name without key size like `rsa` allows all key sizes. An empty list allows
+	// all algorithms.
+	Algorithms []string
+}
+
+// KeyHealth holds the result of the key health check of the signing key.
+type KeyHealth struct {
+	Algorithm         string
+	ExpirationTime    time.Time
+	Expired           bool
+	Revoked           bool
+	NotYetValid       bool
+	ExpiresSoon       bool
+	AlgorithmRejected bool
+	Warnings          []string
+}
+
+// Status returns a short summary of the key health.
+func (h KeyHealth) Status() string {
+	switch {
+	case h.Revoked:
+		return "revoked"
+	case h.Expired:
+		return "expired"
+	case h.NotYetValid:
+		return "not yet valid"
+	case h.AlgorithmRejected:
+		return "algorithm not allowed"
+	case h.ExpiresSoon:
+		return "expires soon"
+	}
+
+	return "ok"
+}
+
+// CheckKeyHealth checks the primary key and the signing key matching Key.Fingerprint
+// against the given policy. Expiry, revocation and the expiry windows are checked at
+// the current time even if a SignatureTime is pinned, only the creation of the keys is
+// checked against the SignatureTime. The result is stored in Key.Health. It returns an
+// error if the key is expired, revoked, not yet valid, expires within the fail window
+// or the algorithm of the signing key is not allowed.
+func (c *Client) CheckKeyHealth(policy KeyPolicy) error {
+	var (
+		health KeyHealth
+		errs   []error
+	)
+
+	now := time.Now()
+
+	signing, err := c.Key.SigningSubkey(c.Key.Fingerprint)
+	if errors.Is(err, ErrFingerprintNotFound) {
+		return err
+	}
+
+	for _, subkey := range c.Key.Subkeys {
+		if !subkey.Primary && subkey.Fingerprint != signing.Fingerprint {
+			continue
+		}
+
+		health.Expired = health.Expired || subkey.Expired
+		health.Revoked = health.Revoked || subkey.Revoked
+		health.NotYetValid = health.NotYetValid || subkey.NotYetValid
+
+		if !subkey.ExpirationTime.IsZero() &&
+			(health.ExpirationTime.IsZero() || subkey.ExpirationTime.Before(health.ExpirationTime)) {
+			health.ExpirationTime = subkey.ExpirationTime
+		}
+	}
+
+	switch {
+	case health.Revoked:
+		errs = append(errs, fmt.Errorf("%w: %s", ErrKeyRevoked, signing.Fingerprint))
+	case health.Expired:
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrKeyExpired, signing.Fingerprint, health.ExpirationTime))
+	case health.NotYetValid:
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrKeyNotYetValid, signing.Fingerprint, c.signingTime().UTC()))
+	case !health.ExpirationTime.IsZero():
+		remaining := health.ExpirationTime.Sub(now)
+
+		if policy.ExpiryFail > 0 && remaining <= policy.ExpiryFail {
+			health.ExpiresSoon = true
+
+			errs = append(errs, fmt.Errorf("%w: %s: %s", ErrKeyExpiresSoon, signing.Fingerprint, health.ExpirationTime))
+		} else if policy.ExpiryWarn > 0 && remaining <= policy.ExpiryWarn {
+			health.ExpiresSoon = true
+			health.Warnings = append(health.Warnings, fmt.Sprintf("key expires at %s", health.ExpirationTime))
+		}
+	}
+
+	health.Algorithm = signing.PolicyName()
+
+	if reason := policy.algorithmViolation(signing); reason != "" {
+		health.AlgorithmRejected = true
+
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrKeyAlgorithmPolicy, health.Algorithm, reason))
+	}
+
+	c.Key.Health = health
+
+	return errors.Join(errs...)
+}
+
+// PolicyName returns the name of the key algorithm used in key policies, e.g.
+// `rsa4096`, `ed25519` or `ecdsa-p256`.
+func (s Subkey) PolicyName() string {
+	name := strings.ToLower(s.Algorithm)
+
+	switch name {
+	case "rsa", "dsa", "elgamal":
+		return name + strconv.Itoa(s.BitLength)
+	case "eddsa", "ed25519", "ed448":
+		if s.Curve == string(packet.Curve448) {
+			return "ed448"
+		}
+
+		return "ed25519"
+	}
+
+	if s.Curve != "" {
+		return name + "-" + strings.ToLower(s.Curve)
+	}
+
+	return name
+}
+
+func (p KeyPolicy) algorithmViolation(subkey Subkey) string {
+	name := subkey.PolicyName()
+
+	if p.MinRSABits > 0 && subkey.Algorithm == "RSA" && subkey.BitLength < p.MinRSABits {
+		return fmt.Sprintf("less than %d bits", p.MinRSABits)
+	}
+
+	if len(p.Algorithms) == 0 {
+		return ""
+	}
+
+	allowed := slices.ContainsFunc(p.Algorithms, func(algo string) bool {
+		algo = strings.ToLower(strings.TrimSpace(algo))
+
+		return algo == name ||
+			algo == strings.TrimRight(name, "0123456789") ||
+			strings.HasPrefix(name, algo+"-")
+	})
+	if !allowed {
+		return fmt.Sprintf("allowed algorithms are %s", strings.Join(p.Algorithms, ", "))
+	}
+
+	return ""
+}
+
+// signingTime returns the time the signatures are created at, which is the
+// SignatureTime if set or the current time otherwise.
+func (c *Client) signingTime() time.Time {
+	if !c.SignatureTime.IsZero() {
+		return c.SignatureTime
+	}
+
+	return time.Now()
+}
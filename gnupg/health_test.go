@@ -0,0 +1,145 @@
+package gnupg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_CheckKeyHealth(t *testing.T) {
+	now := time.Now().UTC()
+
+	newKey := func(primary, signing Subkey) Key {
+		primary.Fingerprint, primary.Primary, primary.CanCertify = "AAAA", true, true
+		signing.Fingerprint, signing.CanSign = "BBBB", true
+
+		return Key{Fingerprint: "BBBB", Subkeys: []Subkey{primary, signing}}
+	}
+
+	rsa4096 := Subkey{Algorithm: "RSA", BitLength: 4096}
+
+	tests := []struct {
+		name       string
+		key        Key
+		policy     KeyPolicy
+		wantStatus string
+		wantWarn   bool
+		wantErr    error
+	}{
+		{
+			name:       "healthy key",
+			key:        newKey(rsa4096, rsa4096),
+			policy:     KeyPolicy{ExpiryWarn: time.Hour, MinRSABits: 3072, Algorithms: []string{"rsa4096", "ed25519"}},
+			wantStatus: "ok",
+		},
+		{
+			name: "expired subkey",
+			key: newKey(rsa4096, Subkey{
+				Algorithm: "RSA", BitLength: 4096, ExpirationTime: now.Add(-time.Hour), Expired: true,
+			}),
+			wantStatus: "expired",
+			wantErr:    ErrKeyExpired,
+		},
+		{
+			name:       "revoked primary key",
+			key:        newKey(Subkey{Algorithm: "RSA", BitLength: 4096, Revoked: true}, rsa4096),
+			wantStatus: "revoked",
+			wantErr:    ErrKeyRevoked,
+		},
+		{
+			name: "expires within warn window",
+			key: newKey(Subkey{
+				Algorithm: "RSA", BitLength: 4096, ExpirationTime: now.Add(24 * time.Hour),
+			}, rsa4096),
+			policy:     KeyPolicy{ExpiryWarn: 48 * time.Hour},
+			wantStatus: "expires soon",
+			wantWarn:   true,
+		},
+		{
+			name: "expires within fail window",
+			key: newKey(rsa4096, Subkey{
+				Algorithm: "RSA", BitLength: 4096, ExpirationTime: now.Add(24 * time.Hour),
+			}),
+			policy:     KeyPolicy{ExpiryWarn: 96 * time.Hour, ExpiryFail: 48 * time.Hour},
+			wantStatus: "expires soon",
+			wantErr:    ErrKeyExpiresSoon,
+		},
+		{
+			name:       "rsa key too small",
+			key:        newKey(rsa4096, Subkey{Algorithm: "RSA", BitLength: 2048}),
+			policy:     KeyPolicy{MinRSABits: 3072},
+			wantStatus: "algorithm not allowed",
+			wantErr:    ErrKeyAlgorithmPolicy,
+		},
+		{
+			name:       "algorithm not allowed",
+			key:        newKey(rsa4096, Subkey{Algorithm: "DSA", BitLength: 3072}),
+			policy:     KeyPolicy{Algorithms: []string{"rsa", "ed25519"}},
+			wantStatus: "algorithm not allowed",
+			wantErr:    ErrKeyAlgorithmPolicy,
+		},
+		{
+			name:       "unknown fingerprint",
+			key:        Key{Fingerprint: "CCCC", Subkeys: []Subkey{{Fingerprint: "AAAA", Primary: true}}},
+			wantStatus: "ok",
+			wantErr:    ErrFingerprintNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{Key: tt.key}
+
+			err := c.CheckKeyHealth(tt.policy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantStatus, c.Key.Health.Status())
+			assert.Equal(t, tt.wantWarn, len(c.Key.Health.Warnings) > 0)
+		})
+	}
+}
+
+func TestSubkey_PolicyName(t *testing.T) {
+	tests := []struct {
+		name   string
+		subkey Subkey
+		want   string
+	}{
+		{
+			name:   "rsa",
+			subkey: Subkey{Algorithm: "RSA", BitLength: 4096},
+			want:   "rsa4096",
+		},
+		{
+			name:   "dsa",
+			subkey: Subkey{Algorithm: "DSA", BitLength: 2048},
+			want:   "dsa2048",
+		},
+		{
+			name:   "legacy eddsa",
+			subkey: Subkey{Algorithm: "EdDSA", Curve: "Curve25519"},
+			want:   "ed25519",
+		},
+		{
+			name:   "ed448",
+			subkey: Subkey{Algorithm: "Ed448", Curve: "Curve448"},
+			want:   "ed448",
+		},
+		{
+			name:   "ecdsa",
+			subkey: Subkey{Algorithm: "ECDSA", Curve: "P256"},
+			want:   "ecdsa-p256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.subkey.PolicyName())
+		})
+	}
+}
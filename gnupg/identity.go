@@ -0,0 +1,120 @@
+package gnupg
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+var (
+	ErrEmailNotFound  = errors.New("no user ID with email found")
+	ErrUserIDNotValid = errors.New("user ID is not valid")
+)
+
+// UserID holds the metadata of a user ID of the key.
+type UserID struct {
+	ID             string
+	Name           string
+	Email          string
+	Comment        string
+	Primary        bool
+	CreationTime   time.Time
+	ExpirationTime time.Time
+	Expired        bool
+	Revoked        bool
+}
+
+// State returns a short description of the validity of the user ID.
+func (u UserID) State() string {
+	switch {
+	case u.Revoked:
+		return "revoked"
+	case u.Expired:
+		return "expired"
+	}
+
+	return "valid"
+}
+
+// RequireEmail checks that the key has a valid user ID with the given email address.
+// The email address is compared case-insensitive. The user IDs are valid at the current
+// time as read by ReadPrivateKey.
+func (k Key) RequireEmail(email string) error {
+	var found *UserID
+
+	for i, uid := range k.UserIDs {
+		if !strings.EqualFold(uid.Email, email) {
+			continue
+		}
+
+		if !uid.Expired && !uid.Revoked {
+			return nil
+		}
+
+		found = &k.UserIDs[i]
+	}
+
+	if found == nil {
+		return fmt.Errorf("%w: %s", ErrEmailNotFound, email)
+	}
+
+	return fmt.Errorf("%w: %s: %s", ErrUserIDNotValid, found.ID, found.State())
+}
+
+// readUserIDs returns the metadata of all user IDs of the entity. The primary user ID
+// comes first, followed by the remaining user IDs in alphabetical order. Expiry and
+// revocation are evaluated at the given current time.
+func readUserIDs(entity *openpgp.Entity, primary *openpgp.Identity, now time.Time) []UserID {
+	uids := make([]UserID, 0, len(entity.Identities))
+
+	for _, identity := range entity.Identities {
+		uid := UserID{
+			ID:      identity.Name,
+			Primary: identity == primary,
+		}
+
+		if identity.UserId != nil {
+			uid.Name = identity.UserId.Name
+			uid.Email = identity.UserId.Email
+			uid.Comment = identity.UserId.Comment
+		}
+
+		sig, err := identity.LatestValidSelfCertification(time.Time{}, nil)
+		if err != nil {
+			// A user ID without valid self-certification can not be used at all.
+			uid.Revoked = true
+			uids = append(uids, uid)
+
+			continue
+		}
+
+		uid.CreationTime = sig.CreationTime.UTC()
+		uid.Expired = sig.SigExpired(now)
+		uid.Revoked = identity.Revoked(sig, now, nil)
+
+		if sig.SigLifetimeSecs != nil && *sig.SigLifetimeSecs != 0 {
+			uid.ExpirationTime = sig.CreationTime.Add(time.Duration(*sig.SigLifetimeSecs) * time.Second).UTC()
+		}
+
+		uids = append(uids, uid)
+	}
+
+	slices.SortFunc(uids, func(a, b UserID) int {
+		if a.Primary != b.Primary {
+			if a.Primary {
+				return -1
+			}
+
+			return 1
+		}
+
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return uids
+}
@@ -0,0 +1,123 @@
+package gnupg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadUserIDs(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("John Doe", "john.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	entity := key.GetEntity()
+	now := time.Now()
+
+	require.NoError(t, entity.AddUserId("Release", "CI", "release@example.com", nil))
+	require.NoError(t, entity.AddUserId("Archive", "", "archive@example.com", &packet.Config{SigLifetimeSecs: 60}))
+
+	_, primary := entity.PrimaryIdentity(now, &packet.Config{})
+	require.NotNil(t, primary)
+
+	uids := readUserIDs(entity, primary, now.Add(time.Hour))
+	require.Len(t, uids, 3)
+
+	assert.Equal(t, "John Doe <john.doe@example.com>", uids[0].ID)
+	assert.True(t, uids[0].Primary)
+	assert.Equal(t, "valid", uids[0].State())
+
+	assert.Equal(t, "Archive <archive@example.com>", uids[1].ID)
+	assert.Equal(t, "expired", uids[1].State())
+	assert.Equal(t, uids[1].CreationTime.Add(time.Minute), uids[1].ExpirationTime)
+
+	assert.Equal(t, "Release", uids[2].Name)
+	assert.Equal(t, "CI", uids[2].Comment)
+	assert.Equal(t, "release@example.com", uids[2].Email)
+	assert.False(t, uids[2].Primary)
+	assert.Equal(t, "valid", uids[2].State())
+}
+
+func TestClient_ReadPrivateKeyUserIDsSignatureTime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	created := &packet.Config{Time: func() time.Time { return now.Add(-3 * time.Hour) }}
+
+	entity, err := openpgp.NewEntity("John Doe", "", "john.doe@example.com", created)
+	require.NoError(t, err)
+	require.NoError(t, entity.AddUserId("Archive", "", "archive@example.com", &packet.Config{
+		Time:            created.Time,
+		SigLifetimeSecs: 3600,
+	}))
+
+	key, err := crypto.NewKeyFromEntity(entity)
+	require.NoError(t, err)
+
+	armored, err := key.Armor()
+	require.NoError(t, err)
+
+	// The user ID expired after the pinned signature time, but is checked at the current time
+	c := &Client{Key: Key{Content: armored}, SignatureTime: now.Add(-150 * time.Minute)}
+	require.NoError(t, c.ReadPrivateKey())
+
+	assert.ErrorIs(t, c.Key.RequireEmail("archive@example.com"), ErrUserIDNotValid)
+	assert.NoError(t, c.Key.RequireEmail("john.doe@example.com"))
+	assert.Equal(t, "valid", c.Key.Subkeys[0].State())
+}
+
+func TestKey_RequireEmail(t *testing.T) {
+	key := Key{
+		UserIDs: []UserID{
+			{ID: "John Doe <john.doe@example.com>", Email: "john.doe@example.com", Primary: true},
+			{ID: "Release <release@example.com>", Email: "release@example.com"},
+			{ID: "Archive <archive@example.com>", Email: "archive@example.com", Expired: true},
+			{ID: "Old <old@example.com>", Email: "old@example.com", Revoked: true},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{
+			name:  "primary user id",
+			email: "john.doe@example.com",
+		},
+		{
+			name:  "case insensitive",
+			email: "Release@Example.com",
+		},
+		{
+			name:    "expired user id",
+			email:   "archive@example.com",
+			wantErr: ErrUserIDNotValid,
+		},
+		{
+			name:    "revoked user id",
+			email:   "old@example.com",
+			wantErr: ErrUserIDNotValid,
+		},
+		{
+			name:    "missing email",
+			email:   "jane.doe@example.com",
+			wantErr: ErrEmailNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := key.RequireEmail(tt.email)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
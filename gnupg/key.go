@@ -2,25 +2,28 @@ package gnupg
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/ProtonMail/gopenpgp/v3/crypto"
-	"golang.org/x/sys/execabs"
-
-	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+	"github.com/rs/zerolog/log"
 )
 
 var (
 	ErrPrimaryIdentityNotFound = errors.New("no primary identity found")
 	ErrReadKeyFailed           = errors.New("failed to read private key")
+	ErrExportKeyFailed         = errors.New("failed to export public key")
 )
 
+const publicKeyFilePerm = 0o644
+
 // IsArmored checks if the given key is armored by trying to parse it.
 // Returns true if the key is armored, false otherwise.
 func IsArmored(key string) bool {
@@ -29,9 +32,19 @@ func IsArmored(key string) bool {
 	return err == nil
 }
 
+// UseKey sets the key used by the client. The keyring used to verify created
+// signatures is reset, as it is derived from the key.
+func (c *Client) UseKey(key Key) {
+	c.Key = key
+	c.keyring = nil
+}
+
 // ReadPrivateKey reads a private key from the given Key struct.
 // It parses the armored key content into a gopenpgp private key.
-// It returns the key ID, creation time, identity, and fingerprint.
+// It returns the key ID, creation time, identity, and fingerprint, and lists all
+// user IDs as well as the primary key and all subkeys with their capabilities, expiry
+// and revocation state. The validity of user IDs and keys is evaluated at the current
+// time, only keys created after the SignatureTime are additionally marked as not yet valid.
 // It returns an error if the key could not be parsed or the primary identity was not found.
 func (c *Client) ReadPrivateKey() error {
 	gkey, err := crypto.NewKeyFromArmored(c.Key.Content)
@@ -46,14 +59,106 @@ func (c *Client) ReadPrivateKey() error {
 	c.Key.CreationTime = primary.CreationTime.UTC()
 	c.Key.Fingerprint = strings.ToUpper(hex.EncodeToString(primary.Fingerprint))
 
-	var config *packet.Config
+	now := time.Now()
 
-	_, identity := entity.PrimaryIdentity(config.Now(), &packet.Config{})
+	_, identity := entity.PrimaryIdentity(now, &packet.Config{})
 	if identity == nil {
 		return ErrPrimaryIdentityNotFound
 	}
 
 	c.Key.Identity = identity.Name
+	c.Key.UserIDs = readUserIDs(entity, identity, now)
+	c.Key.Subkeys = readSubkeys(entity, now, c.signingTime())
+
+	return nil
+}
+
+// ParseIdentity splits an OpenPGP user ID in the format `Name (Comment) <email>`
+// into the name and the email address. Missing parts are returned empty.
+func ParseIdentity(identity string) (string, string) {
+	name, email := identity, ""
+
+	if start := strings.LastIndex(identity, "<"); start >= 0 {
+		if end := strings.Index(identity[start:], ">"); end > 0 {
+			name = identity[:start]
+			email = identity[start+1 : start+end]
+		}
+	}
+
+	if start := strings.Index(name, "("); start >= 0 {
+		if end := strings.LastIndex(name, ")"); end > start {
+			name = name[:start] + name[end+1:]
+		}
+	}
+
+	return strings.TrimSpace(name), strings.TrimSpace(email)
+}
+
+// AlgorithmName returns the human readable name of the given public key algorithm.
+func AlgorithmName(algo packet.PublicKeyAlgorithm) string {
+	switch algo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly, packet.PubKeyAlgoRSAEncryptOnly:
+		return "RSA"
+	case packet.PubKeyAlgoDSA:
+		return "DSA"
+	case packet.PubKeyAlgoElGamal:
+		return "ElGamal"
+	case packet.PubKeyAlgoECDH:
+		return "ECDH"
+	case packet.PubKeyAlgoECDSA:
+		return "ECDSA"
+	case packet.PubKeyAlgoEdDSA:
+		return "EdDSA"
+	case packet.PubKeyAlgoX25519:
+		return "X25519"
+	case packet.PubKeyAlgoX448:
+		return "X448"
+	case packet.PubKeyAlgoEd25519:
+		return "Ed25519"
+	case packet.PubKeyAlgoEd448:
+		return "Ed448"
+	}
+
+	return fmt.Sprintf("unknown (%d)", algo)
+}
+
+// SigningKeyAlgorithm returns the public key algorithm of the primary key or
+// subkey matching Key.Fingerprint.
+func (c *Client) SigningKeyAlgorithm() (packet.PublicKeyAlgorithm, error) {
+	gkey, err := crypto.NewKeyFromArmored(c.Key.Content)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrReadKeyFailed, err)
+	}
+
+	entity := gkey.GetEntity()
+
+	if c.Key.Fingerprint == "" {
+		return entity.PrimaryKey.PubKeyAlgo, nil
+	}
+
+	pk, ok := findPublicKey(entity, c.Key.Fingerprint)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrSigningKeyNotFound, c.Key.Fingerprint)
+	}
+
+	return pk.PubKeyAlgo, nil
+}
+
+// ExportPublicKey writes the armored public part of the private key to the given path.
+func (c *Client) ExportPublicKey(path string) error {
+	gkey, err := crypto.NewKeyFromArmored(c.Key.Content)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReadKeyFailed, err)
+	}
+
+	armored, err := gkey.GetArmoredPublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExportKeyFailed, err)
+	}
+
+	if err := os.WriteFile(path, []byte(armored+"\n"), publicKeyFilePerm); err != nil {
+		return fmt.Errorf("%w: %w", ErrExportKeyFailed, err)
+	}
 
 	return nil
 }
@@ -61,29 +166,37 @@ func (c *Client) ReadPrivateKey() error {
 // ImportKey imports a GPG key provided via the Key.Content field.
 // It runs the gpg --import command to import the key into the keyring.
 // Returns an error if the import command fails.
-func (c *Client) ImportKey() error {
+func (c *Client) ImportKey(ctx context.Context) error {
 	args := []string{
 		"--batch",
+		"--status-fd",
+		statusFD,
 		"--import",
 		"-",
 	}
 
-	absBin, err := execabs.LookPath(c.gpgBin)
+	cmd, err := c.newCommand(ctx, c.gpgBin, args...)
 	if err != nil {
-		return fmt.Errorf("could not find executable %q: %w", c.gpgconfBin, err)
+		return err
 	}
 
-	cmd := plugin_exec.Command(absBin, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.TraceWriter = c.traceWriter
-	cmd.Env = append(cmd.Env, c.Env...)
 	cmd.Stdin = strings.NewReader(c.Key.Content)
 
-	if err := cmd.Run(); err != nil {
+	statuses, err := cmd.runStatus()
+	if err != nil {
 		return fmt.Errorf("failed to import gpg key: %w", err)
 	}
 
+	for _, status := range statuses {
+		if status.Keyword != StatusImportOK {
+			continue
+		}
+
+		if res, err := status.ImportOK(); err == nil {
+			log.Debug().Str("fingerprint", res.Fingerprint).Int("reason", res.Reason).Msg("key imported")
+		}
+	}
+
 	return nil
 }
 
@@ -91,7 +204,7 @@ func (c *Client) ImportKey() error {
 // It runs the gpg --edit-key command to set the trust level to the
 // provided level string. Valid levels are "unknown", "never", "marginal",
 // "full", "ultimate". Returns an error if the command fails.
-func (c *Client) SetTrustLevel(level string) error {
+func (c *Client) SetTrustLevel(ctx context.Context, level string) error {
 	valid := []string{"unknown", "never", "marginal", "full", "ultimate"}
 
 	if !slices.Contains(valid, level) {
@@ -107,19 +220,14 @@ func (c *Client) SetTrustLevel(level string) error {
 		c.Key.ID,
 	}
 
-	absBin, err := execabs.LookPath(c.gpgBin)
+	cmd, err := c.newCommand(ctx, c.gpgBin, args...)
 	if err != nil {
-		return fmt.Errorf("could not find executable %q: %w", c.gpgconfBin, err)
+		return err
 	}
 
-	cmd := plugin_exec.Command(absBin, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.TraceWriter = c.traceWriter
-	cmd.Env = append(cmd.Env, c.Env...)
 	cmd.Stdin = bytes.NewBuffer([]byte(fmt.Sprintf("trust\n%s\ny\nquit\n", level)))
 
-	if err := cmd.Run(); err != nil {
+	if err := cmd.run(); err != nil {
 		return fmt.Errorf("failed to set key owner trust: %w", err)
 	}
 
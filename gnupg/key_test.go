@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -167,6 +170,9 @@ func TestReadPrivateKey(t *testing.T) {
 		},
 	}
 
+	// Keep the default homedir of the client out of the real home directory
+	t.Setenv("HOME", t.TempDir())
+
 	for _, tt := range tests {
 		gpgclient, _ := New(tt.key, "")
 
@@ -185,6 +191,9 @@ func TestReadPrivateKey(t *testing.T) {
 			assert.Equal(t, gpgclient.Key.Fingerprint, testKeyFingerprint)
 			assert.Equal(t, gpgclient.Key.Identity, testKeyIdentity)
 			assert.Equal(t, gpgclient.Key.CreationTime, testKeyCreation.UTC())
+			assert.NotEmpty(t, gpgclient.Key.Subkeys)
+			assert.Equal(t, gpgclient.Key.Subkeys[0].Fingerprint, testKeyFingerprint)
+			assert.True(t, gpgclient.Key.Subkeys[0].Primary)
 		})
 	}
 }
@@ -203,7 +212,7 @@ func TestClient_ImportKey(t *testing.T) {
 			key:  testPrivateKey,
 			bin:  os.Args[0],
 			env:  []string{"GO_TEST_MODE=pass"},
-			want: []string{"gnupg.test --batch --import -"},
+			want: []string{"gnupg.test --batch --status-fd 3 --import -"},
 		},
 		{
 			name:    "gpg binary not found",
@@ -225,7 +234,7 @@ func TestClient_ImportKey(t *testing.T) {
 				},
 			}
 
-			err := c.ImportKey()
+			err := c.ImportKey(t.Context())
 
 			for _, l := range tt.want {
 				assert.Contains(t, buf.String(), l)
@@ -283,7 +292,7 @@ func TestClient_SetTrustLevel(t *testing.T) {
 				},
 			}
 
-			err := c.SetTrustLevel(tt.level)
+			err := c.SetTrustLevel(t.Context(), tt.level)
 
 			for _, l := range tt.want {
 				assert.Contains(t, buf.String(), l)
@@ -299,3 +308,124 @@ func TestClient_SetTrustLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ExportPublicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{
+			name: "success",
+			key:  testPrivateKey,
+		},
+		{
+			name:    "invalid key",
+			key:     "invalid",
+			wantErr: ErrReadKeyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "public.asc")
+			c := &Client{
+				Key: Key{
+					Content: tt.key,
+				},
+			}
+
+			err := c.ExportPublicKey(path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			got, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			pub, err := crypto.NewKeyFromArmored(string(got))
+			assert.NoError(t, err)
+			assert.False(t, pub.IsPrivate())
+			assert.Equal(t, strings.ToLower(testKeyFingerprint), pub.GetFingerprint())
+		})
+	}
+}
+
+func TestClient_SigningKeyAlgorithm(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		fingerprint string
+		want        string
+		wantErr     error
+	}{
+		{
+			name: "primary key",
+			key:  testPrivateKey,
+			want: "RSA",
+		},
+		{
+			name:        "fingerprint",
+			key:         testPrivateKey,
+			fingerprint: strings.ToLower(testKeyFingerprint),
+			want:        "RSA",
+		},
+		{
+			name:        "unknown fingerprint",
+			key:         testPrivateKey,
+			fingerprint: "0000000000000000000000000000000000000000",
+			wantErr:     ErrSigningKeyNotFound,
+		},
+		{
+			name:    "invalid key",
+			key:     "invalid",
+			wantErr: ErrReadKeyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				Key: Key{
+					Content:     tt.key,
+					Fingerprint: tt.fingerprint,
+				},
+			}
+
+			algo, err := c.SigningKeyAlgorithm()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, AlgorithmName(algo))
+		})
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		identity  string
+		wantName  string
+		wantEmail string
+	}{
+		{identity: "John Doe <john.doe@example.com>", wantName: "John Doe", wantEmail: "john.doe@example.com"},
+		{identity: "John Doe (CI) <john.doe@example.com>", wantName: "John Doe", wantEmail: "john.doe@example.com"},
+		{identity: "<john.doe@example.com>", wantName: "", wantEmail: "john.doe@example.com"},
+		{identity: "John Doe", wantName: "John Doe", wantEmail: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.identity, func(t *testing.T) {
+			name, email := ParseIdentity(tt.identity)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantEmail, email)
+		})
+	}
+}
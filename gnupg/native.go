@@ -0,0 +1,363 @@
+package gnupg
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+)
+
+var (
+	ErrUnlockKeyFailed    = errors.New("failed to unlock private key")
+	ErrSigningKeyNotFound = errors.New("no signing key found for fingerprint")
+)
+
+const (
+	armorTypeSignature = "PGP SIGNATURE"
+	armorTypeMessage   = "PGP MESSAGE"
+
+	// Signature classes of binary and canonical text documents as reported by gpg.
+	sigClassBinary = "00"
+	sigClassText   = "01"
+)
+
+// signFileNative signs the file at the given path in-process with the parsed
+// private key. The signature is written to the output path in the same format
+// gpg would use for the given flags.
+func (c *Client) signFileNative(
+	ctx context.Context, armor, detachSign, clearSign bool, path, output string,
+) (SignatureCreated, error) {
+	key, err := c.unlockSigningKey()
+	if err != nil {
+		return SignatureCreated{}, err
+	}
+
+	req := SignRequest{
+		Path:       path,
+		Output:     output,
+		Armor:      armor,
+		DetachSign: detachSign,
+		ClearSign:  clearSign,
+	}
+
+	return key.sign(ctx, req)
+}
+
+// signNative signs the file of the request with the given unlocked entity and returns
+// the metadata of the created signature like gpg reports it. The creation time of the
+// signature is fixed before signing. Reading the file is stopped if the context is
+// canceled.
+func signNative(
+	ctx context.Context, entity *openpgp.Entity, config *packet.Config, req SignRequest,
+) (SignatureCreated, error) {
+	sig := SignatureCreated{Type: SignatureInline, Class: sigClassBinary}
+
+	// Signatures are created with a precision of seconds
+	at := config.Now().UTC().Truncate(time.Second)
+	reqConfig := *config
+	reqConfig.Time = func() time.Time { return at }
+
+	key, ok := entity.SigningKeyById(at, reqConfig.SigningKey(), &reqConfig)
+	if !ok {
+		return sig, fmt.Errorf("failed to sign file: %w: no valid signing key at %s",
+			ErrSigningKeyNotFound, at.Format(time.RFC3339))
+	}
+
+	file, err := os.Open(req.Path)
+	if err != nil {
+		return sig, fmt.Errorf("failed to sign file: %w", err)
+	}
+	defer file.Close()
+
+	in := &contextReader{ctx: ctx, r: file}
+
+	out, err := os.Create(req.Output)
+	if err != nil {
+		return sig, fmt.Errorf("failed to sign file: %w", err)
+	}
+	defer out.Close()
+
+	switch {
+	case req.DetachSign:
+		sig.Type = SignatureDetached
+		err = writeDetachSignature(out, entity, in, req.Armor, &reqConfig)
+	case req.ClearSign:
+		sig.Type = SignatureCleartext
+		sig.Class = sigClassText
+		err = writeClearSignature(out, entity, in, &reqConfig)
+	default:
+		err = writeInlineSignature(out, entity, in, req.Armor, filepath.Base(req.Path), &reqConfig)
+	}
+
+	if err != nil {
+		return sig, fmt.Errorf("failed to sign file: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return sig, err
+	}
+
+	sig.PubKeyAlgo = key.PublicKey.PubKeyAlgo
+	sig.Timestamp = at
+	sig.Fingerprint = strings.ToUpper(hex.EncodeToString(key.PublicKey.Fingerprint))
+
+	return sig, nil
+}
+
+// unlockedKeys caches the unlocked private keys of a client and its copies, so the key
+// is parsed and unlocked only once even if files are signed one by one or concurrently.
+type unlockedKeys struct {
+	mu   sync.Mutex
+	keys map[unlockedKeyID]*unlockedKey
+}
+
+type unlockedKeyID struct {
+	content       string
+	passphrase    string
+	fingerprint   string
+	signatureTime time.Time
+}
+
+// unlockedKey is an unlocked private key with the config to sign with it. Signing is
+// serialized, as go-crypto caches the verification of the self-signatures in the entity
+// and is not safe for concurrent use.
+type unlockedKey struct {
+	mu     sync.Mutex
+	entity *openpgp.Entity
+	config *packet.Config
+}
+
+func newUnlockedKeys() *unlockedKeys {
+	return &unlockedKeys{keys: make(map[unlockedKeyID]*unlockedKey)}
+}
+
+// clear drops all cached keys.
+func (u *unlockedKeys) clear() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	clear(u.keys)
+}
+
+// sign signs the file of the request with the unlocked key.
+func (k *unlockedKey) sign(ctx context.Context, req SignRequest) (SignatureCreated, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return signNative(ctx, k.entity, k.config, req)
+}
+
+// unlockSigningKey returns the unlocked signing key from the cache of the client, or
+// unlocks it with readSigningKey. Clients without cache unlock the key on every call.
+func (c *Client) unlockSigningKey() (*unlockedKey, error) {
+	if c.unlocked == nil {
+		entity, config, err := c.readSigningKey()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unlockedKey{entity: entity, config: config}, nil
+	}
+
+	id := unlockedKeyID{
+		content:       c.Key.Content,
+		passphrase:    c.Key.Passphrase,
+		fingerprint:   c.Key.Fingerprint,
+		signatureTime: c.SignatureTime,
+	}
+
+	c.unlocked.mu.Lock()
+	defer c.unlocked.mu.Unlock()
+
+	if key, ok := c.unlocked.keys[id]; ok {
+		return key, nil
+	}
+
+	entity, config, err := c.readSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &unlockedKey{entity: entity, config: config}
+	c.unlocked.keys[id] = key
+
+	return key, nil
+}
+
+// readSigningKey parses the private key, unlocks it with the configured passphrase
+// and returns the entity together with a config that selects the signing key
+// matching Key.Fingerprint and applies the SignatureTime.
+func (c *Client) readSigningKey() (*openpgp.Entity, *packet.Config, error) {
+	gkey, err := crypto.NewKeyFromArmored(c.Key.Content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrReadKeyFailed, err)
+	}
+
+	locked, err := gkey.IsLocked()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrUnlockKeyFailed, err)
+	}
+
+	if locked {
+		gkey, err = gkey.Unlock([]byte(c.Key.Passphrase))
+		if err != nil {
+			// Report the cause like gpg does to allow the same failure handling for both backends
+			cause := ErrBadPassphrase
+			if c.Key.Passphrase == "" {
+				cause = ErrMissingPassphrase
+			}
+
+			return nil, nil, fmt.Errorf("%w: %w: %w", ErrUnlockKeyFailed, cause, err)
+		}
+	}
+
+	entity := gkey.GetEntity()
+	config := &packet.Config{}
+
+	// Pin the creation time and disable the random salt notation of signatures
+	// to create reproducible signatures
+	if !c.SignatureTime.IsZero() {
+		randomize := false
+		signatureTime := c.SignatureTime
+
+		config.Time = func() time.Time { return signatureTime }
+		config.NonDeterministicSignaturesViaNotation = &randomize
+	}
+
+	if c.Key.Fingerprint != "" {
+		pk, ok := findPublicKey(entity, c.Key.Fingerprint)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrSigningKeyNotFound, c.Key.Fingerprint)
+		}
+
+		config.SigningKeyId = pk.KeyId
+	}
+
+	return entity, config, nil
+}
+
+// findPublicKey returns the primary key or subkey that matches the given hex
+// encoded fingerprint.
+func findPublicKey(entity *openpgp.Entity, fingerprint string) (*packet.PublicKey, bool) {
+	fingerprint = strings.ToUpper(fingerprint)
+
+	if strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)) == fingerprint {
+		return entity.PrimaryKey, true
+	}
+
+	for _, subkey := range entity.Subkeys {
+		if strings.ToUpper(hex.EncodeToString(subkey.PublicKey.Fingerprint)) == fingerprint {
+			return subkey.PublicKey, true
+		}
+	}
+
+	return nil, false
+}
+
+func writeDetachSignature(
+	w io.Writer, entity *openpgp.Entity, message io.Reader, armored bool, config *packet.Config,
+) error {
+	if !armored {
+		return openpgp.DetachSign(w, []*openpgp.Entity{entity}, message, config)
+	}
+
+	return writeArmored(w, armorTypeSignature, func(aw io.Writer) error {
+		return openpgp.DetachSign(aw, []*openpgp.Entity{entity}, message, config)
+	})
+}
+
+func writeInlineSignature(
+	w io.Writer, entity *openpgp.Entity, message io.Reader, armored bool, name string, config *packet.Config,
+) error {
+	sign := func(sw io.Writer) error {
+		hints := &openpgp.FileHints{FileName: name}
+
+		pw, err := openpgp.Sign(sw, []*openpgp.Entity{entity}, hints, config)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(pw, message); err != nil {
+			return err
+		}
+
+		return pw.Close()
+	}
+
+	if !armored {
+		return sign(w)
+	}
+
+	return writeArmored(w, armorTypeMessage, sign)
+}
+
+func writeClearSignature(w io.Writer, entity *openpgp.Entity, message io.Reader, config *packet.Config) error {
+	key, ok := entity.SigningKeyById(config.Now(), config.SigningKey(), config)
+	if !ok {
+		return fmt.Errorf("%w: no valid signing key at %s", ErrSigningKeyNotFound, config.Now().UTC().Format(time.RFC3339))
+	}
+
+	pw, err := clearsign.Encode(w, key.PrivateKey, config)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(pw, message); err != nil {
+		return err
+	}
+
+	if err := pw.Close(); err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("\n"))
+
+	return err
+}
+
+// writeArmored wraps the output of fn into an ASCII armor block of the given type.
+// A trailing newline is added to match the output of gpg.
+func writeArmored(w io.Writer, blockType string, fn func(io.Writer) error) error {
+	aw, err := armor.Encode(w, blockType, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(aw); err != nil {
+		return err
+	}
+
+	if err := aw.Close(); err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte("\n"))
+
+	return err
+}
+
+// contextReader is a reader that fails once the context is canceled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
@@ -0,0 +1,243 @@
+package gnupg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SignFileNative(t *testing.T) {
+	content := []byte("hello world\n")
+
+	tests := []struct {
+		name        string
+		armor       bool
+		detach      bool
+		clear       bool
+		passphrase  string
+		fingerprint string
+		wantFile    string
+		wantPrefix  string
+		wantErr     error
+	}{
+		{
+			name:       "sign file",
+			passphrase: testPassphrase,
+			wantFile:   "file.txt.gpg",
+		},
+		{
+			name:       "sign file with armor",
+			armor:      true,
+			passphrase: testPassphrase,
+			wantFile:   "file.txt.asc",
+			wantPrefix: "-----BEGIN PGP MESSAGE-----\n\n",
+		},
+		{
+			name:       "detach sign file",
+			detach:     true,
+			passphrase: testPassphrase,
+			wantFile:   "file.txt.sig",
+		},
+		{
+			name:        "detach sign file with armor",
+			armor:       true,
+			detach:      true,
+			passphrase:  testPassphrase,
+			fingerprint: testKeyFingerprint,
+			wantFile:    "file.txt.asc",
+			wantPrefix:  "-----BEGIN PGP SIGNATURE-----\n\n",
+		},
+		{
+			name:       "clear sign file",
+			clear:      true,
+			passphrase: testPassphrase,
+			wantFile:   "file.txt.asc",
+			wantPrefix: "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA256\n\nhello world\n",
+		},
+		{
+			name:       "invalid passphrase",
+			detach:     true,
+			passphrase: "invalid",
+			wantErr:    ErrUnlockKeyFailed,
+		},
+		{
+			name:       "invalid passphrase reported as bad passphrase",
+			detach:     true,
+			passphrase: "invalid",
+			wantErr:    ErrBadPassphrase,
+		},
+		{
+			name:    "missing passphrase",
+			detach:  true,
+			wantErr: ErrMissingPassphrase,
+		},
+		{
+			name:        "unknown fingerprint",
+			detach:      true,
+			passphrase:  testPassphrase,
+			fingerprint: "0000000000000000000000000000000000000000",
+			wantErr:     ErrSigningKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			require.NoError(t, os.WriteFile(path, content, 0o600))
+
+			c := &Client{
+				Backend: BackendNative,
+				Key: Key{
+					Content:     testPrivateKey,
+					Passphrase:  tt.passphrase,
+					Fingerprint: tt.fingerprint,
+				},
+			}
+
+			err := c.SignFile(t.Context(), tt.armor, tt.detach, tt.clear, path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			sigPath := filepath.Join(filepath.Dir(path), tt.wantFile)
+			assert.Equal(t, sigPath, SignaturePath(tt.armor, tt.detach, tt.clear, path))
+
+			sig, err := os.ReadFile(sigPath)
+			require.NoError(t, err)
+
+			if tt.wantPrefix != "" {
+				assert.True(t, strings.HasPrefix(string(sig), tt.wantPrefix))
+				assert.True(t, strings.HasSuffix(string(sig), "-----\n"))
+			}
+
+			pubKey, err := crypto.NewKeyFromArmored(testPublicKey)
+			require.NoError(t, err)
+
+			verifier, err := crypto.PGP().Verify().VerificationKey(pubKey).New()
+			require.NoError(t, err)
+
+			encoding := crypto.Bytes
+			if tt.armor {
+				encoding = crypto.Armor
+			}
+
+			switch {
+			case tt.detach:
+				res, err := verifier.VerifyDetached(content, sig, encoding)
+				require.NoError(t, err)
+				assert.NoError(t, res.SignatureError())
+			case tt.clear:
+				res, err := verifier.VerifyCleartext(sig)
+				require.NoError(t, err)
+				assert.NoError(t, res.SignatureError())
+			default:
+				res, err := verifier.VerifyInline(sig, encoding)
+				require.NoError(t, err)
+				assert.NoError(t, res.SignatureError())
+				assert.Equal(t, content, res.Bytes())
+			}
+		})
+	}
+}
+
+func TestClient_SignFileNativeSignatureTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0o600))
+
+	signatureTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &Client{
+		Backend:       BackendNative,
+		SignatureTime: signatureTime,
+		Key: Key{
+			Content:    testPrivateKey,
+			Passphrase: testPassphrase,
+		},
+	}
+
+	var sigs [][]byte
+
+	for _, output := range []string{path + ".1.sig", path + ".2.sig"} {
+		created, err := c.SignFileTo(t.Context(), false, true, false, path, output)
+		require.NoError(t, err)
+		assert.Equal(t, SignatureDetached, created.Type)
+		assert.Equal(t, signatureTime.UTC(), created.Timestamp)
+		assert.NotEmpty(t, created.Fingerprint)
+
+		sig, err := os.ReadFile(output)
+		require.NoError(t, err)
+
+		sigs = append(sigs, sig)
+	}
+
+	assert.Equal(t, sigs[0], sigs[1])
+
+	p, err := packet.Read(bytes.NewReader(sigs[0]))
+	require.NoError(t, err)
+
+	sig, ok := p.(*packet.Signature)
+	require.True(t, ok)
+	assert.Equal(t, signatureTime, sig.CreationTime.UTC())
+}
+
+func TestClient_SignFileNativeUnlockedOnce(t *testing.T) {
+	tmpDir := t.TempDir()
+	c := &Client{
+		Backend:  BackendNative,
+		unlocked: newUnlockedKeys(),
+		Key: Key{
+			Content:    testPrivateKey,
+			Passphrase: testPassphrase,
+		},
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 8)
+
+	for i := range errs {
+		path := filepath.Join(tmpDir, fmt.Sprintf("file-%d.txt", i))
+		require.NoError(t, os.WriteFile(path, []byte(path), 0o600))
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, errs[i] = c.WithOutput(io.Discard).SignFileTo(t.Context(), false, true, false, path, path+".sig")
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, c.unlocked.keys, 1)
+
+	// A wrong passphrase is not cached
+	path := filepath.Join(tmpDir, "file-0.txt")
+	c.Key.Passphrase = "invalid"
+
+	_, err := c.SignFileTo(t.Context(), false, true, false, path, path+".invalid.sig")
+	assert.ErrorIs(t, err, ErrBadPassphrase)
+	assert.Len(t, c.unlocked.keys, 1)
+
+	require.NoError(t, c.Cleanup(t.Context()))
+	assert.Empty(t, c.unlocked.keys)
+}
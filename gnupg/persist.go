@@ -0,0 +1,178 @@
+package gnupg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/execabs"
+)
+
+var (
+	ErrPersistPassphrase = errors.New("persisting the homedir of a key with passphrase requires a preset passphrase")
+	ErrGPGConfConflict   = errors.New("conflicting option in existing gpg.conf")
+)
+
+const (
+	gpgConfFile        = "gpg.conf"
+	gpgWrapperFile     = "gpg-wrapper"
+	gpgWrapperPerm     = 0o700
+	passphraseFileName = "gpgsign-passphrase-*"
+)
+
+// PersistHomedir prepares the home directory to be used by later processes that do not
+// know about the client, e.g. `git tag -s` in a following pipeline step. The home
+// directory is configured with WriteGPGProgram and kept by Cleanup afterwards together
+// with its gpg-agent. As the passphrase is never written to a persisted home directory,
+// a key with passphrase requires the passphrase to be preset in the gpg-agent with
+// PresetPassphrase. The gpg-agent and the preset passphrase do not outlive the container,
+// so a following step in a new container has to preset the passphrase again. The secret
+// key stays in the home directory. The path of the wrapper script is returned.
+func (c *Client) PersistHomedir() (string, error) {
+	if c.Key.Passphrase != "" && (!c.preset || !c.agent) {
+		return "", ErrPersistPassphrase
+	}
+
+	wrapper, err := c.WriteGPGProgram()
+	if err != nil {
+		return "", err
+	}
+
+	c.persist = true
+
+	return wrapper, nil
+}
+
+// WriteGPGProgram configures gpg in the home directory to run without prompting and
+// writes a wrapper script that calls gpg with the home directory. The wrapper can be used
+// as `gpg.program` for git. Unless the passphrase was preset with PresetPassphrase, it is
+// written to a temporary file outside of the home directory, which is passed by the
+// wrapper and removed by Cleanup. The path of the wrapper script is returned.
+func (c *Client) WriteGPGProgram() (string, error) {
+	homedir, err := filepath.Abs(c.Homedir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve homedir: %w", err)
+	}
+
+	gpgBin, err := execabs.LookPath(c.gpgBin)
+	if err != nil {
+		return "", fmt.Errorf("could not find executable %q: %w", c.gpgBin, err)
+	}
+
+	gpgBin, err = filepath.Abs(gpgBin)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve gpg path: %w", err)
+	}
+
+	if err := appendGPGConf(filepath.Join(homedir, gpgConfFile), "batch", "no-tty", "pinentry-mode loopback"); err != nil {
+		return "", err
+	}
+
+	args := []string{shellQuote(gpgBin), "--homedir", shellQuote(homedir)}
+
+	// A preset passphrase is read from the cache of the gpg-agent and not written to disk
+	if c.Key.Passphrase != "" && !c.preset {
+		path, err := c.writePassphraseFile()
+		if err != nil {
+			return "", err
+		}
+
+		args = append(args, "--passphrase-file", shellQuote(path))
+	}
+
+	wrapper := filepath.Join(homedir, gpgWrapperFile)
+	script := fmt.Sprintf("#!/bin/sh\nexec %s \"$@\"\n", strings.Join(args, " "))
+
+	if err := os.WriteFile(wrapper, []byte(script), gpgWrapperPerm); err != nil {
+		return "", fmt.Errorf("failed to write gpg wrapper: %w", err)
+	}
+
+	return wrapper, nil
+}
+
+// writePassphraseFile writes the passphrase to a temporary file outside of the home
+// directory, which is removed by Cleanup. An existing file is reused.
+func (c *Client) writePassphraseFile() (string, error) {
+	if c.passphraseFile != "" {
+		return c.passphraseFile, nil
+	}
+
+	file, err := os.CreateTemp("", passphraseFileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create passphrase file: %w", err)
+	}
+
+	c.passphraseFile = file.Name()
+
+	_, err = file.WriteString(c.Key.Passphrase)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to write passphrase file: %w", err)
+	}
+
+	return c.passphraseFile, nil
+}
+
+// appendGPGConf appends the given options to the gpg.conf at the given path unless
+// they are already set. An existing option with a different value is a conflict.
+func appendGPGConf(path string, options ...string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read gpg.conf: %w", err)
+	}
+
+	existing := make(map[string]string)
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, " ")
+		existing[name] = strings.TrimSpace(value)
+	}
+
+	var missing []string
+
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, " ")
+
+		current, ok := existing[name]
+		if !ok {
+			missing = append(missing, option)
+
+			continue
+		}
+
+		if current != value {
+			return fmt.Errorf("%w: %s %s", ErrGPGConfConflict, name, current)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+
+	data = append(data, strings.Join(missing, "\n")+"\n"...)
+
+	if err := os.WriteFile(path, data, strictFilePerm); err != nil {
+		return fmt.Errorf("failed to write gpg.conf: %w", err)
+	}
+
+	return nil
+}
+
+// shellQuote quotes the given value to be used as a single word in a shell script.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
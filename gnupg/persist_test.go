@@ -0,0 +1,128 @@
+package gnupg
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PersistHomedir(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		preset     bool
+		wantErr    error
+	}{
+		{
+			name: "without passphrase",
+		},
+		{
+			name:       "with preset passphrase",
+			passphrase: "secret",
+			preset:     true,
+		},
+		{
+			name:       "with passphrase not preset",
+			passphrase: "secret",
+			wantErr:    ErrPersistPassphrase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			homedir := filepath.Join(t.TempDir(), "gnupg's home")
+			c := &Client{
+				gpgBin: os.Args[0],
+				Key:    Key{Passphrase: tt.passphrase},
+				agent:  tt.preset,
+				preset: tt.preset,
+			}
+			require.NoError(t, c.SetHomedir(homedir))
+
+			wrapper, err := c.PersistHomedir()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.NoFileExists(t, filepath.Join(homedir, "gpg.conf"))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			conf, err := os.ReadFile(filepath.Join(homedir, "gpg.conf"))
+			require.NoError(t, err)
+			assert.Equal(t, "batch\nno-tty\npinentry-mode loopback\n", string(conf))
+
+			script, err := os.ReadFile(wrapper)
+			require.NoError(t, err)
+			assert.NotContains(t, string(script), "--passphrase-file")
+
+			if tt.passphrase != "" {
+				assert.NotContains(t, string(script), tt.passphrase)
+			}
+
+			assert.NoError(t, c.Cleanup(t.Context()))
+			assert.DirExists(t, homedir)
+		})
+	}
+}
+
+func TestClient_WriteGPGProgram(t *testing.T) {
+	homedir := filepath.Join(t.TempDir(), "it's $(home)")
+	c := &Client{
+		gpgBin: os.Args[0],
+		Key:    Key{Passphrase: "secret"},
+	}
+	require.NoError(t, c.SetHomedir(homedir))
+
+	existing := "# custom config\nkeyid-format long\nbatch"
+	require.NoError(t, os.WriteFile(filepath.Join(homedir, "gpg.conf"), []byte(existing), 0o600))
+
+	wrapper, err := c.WriteGPGProgram()
+	require.NoError(t, err)
+
+	conf, err := os.ReadFile(filepath.Join(homedir, "gpg.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, existing+"\nno-tty\npinentry-mode loopback\n", string(conf))
+
+	// The passphrase is written outside of the homedir and removed on cleanup
+	require.NotEmpty(t, c.passphraseFile)
+	assert.NotContains(t, c.passphraseFile, homedir)
+
+	passphrase, err := os.ReadFile(c.passphraseFile)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(passphrase))
+
+	// The wrapper passes the quoted homedir to the resolved gpg binary
+	cmd := exec.Command(wrapper, "--homedir-check")
+	cmd.Env = append(os.Environ(), "GO_TEST_MODE=args")
+
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "--homedir "+homedir+" --passphrase-file "+c.passphraseFile+" --homedir-check")
+
+	path := c.passphraseFile
+
+	require.NoError(t, c.Cleanup(t.Context()))
+	assert.NoFileExists(t, path)
+	assert.NoDirExists(t, homedir)
+}
+
+func TestClient_WriteGPGProgramConflict(t *testing.T) {
+	homedir := t.TempDir()
+	c := &Client{gpgBin: os.Args[0], Homedir: homedir}
+
+	conf := filepath.Join(homedir, "gpg.conf")
+	require.NoError(t, os.WriteFile(conf, []byte("pinentry-mode ask\n"), 0o600))
+
+	_, err := c.WriteGPGProgram()
+	assert.ErrorIs(t, err, ErrGPGConfConflict)
+
+	data, err := os.ReadFile(conf)
+	require.NoError(t, err)
+	assert.Equal(t, "pinentry-mode ask\n", string(data))
+}
@@ -1,23 +1,60 @@
 package gnupg
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
-	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
-	"golang.org/x/sys/execabs"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 )
 
+var ErrCombineSignatures = errors.New("failed to combine signatures")
+
+// SignaturePath returns the path of the signature file gpg creates for the
+// given input path and signing flags.
+func SignaturePath(armor, detachSign, clearSign bool, path string) string {
+	switch {
+	case clearSign, armor:
+		return path + ".asc"
+	case detachSign:
+		return path + ".sig"
+	default:
+		return path + ".gpg"
+	}
+}
+
 // SignFile signs the file at the given path with the configured key.
 // It supports detached, cleartext, and normal signing based on the
-// detach and clear arguments.
-func (c *Client) SignFile(armor, detachSign, clearSign bool, path string) error {
+// detach and clear arguments. The signature is written to the default
+// location returned by SignaturePath.
+func (c *Client) SignFile(ctx context.Context, armor, detachSign, clearSign bool, path string) error {
+	_, err := c.SignFileTo(ctx, armor, detachSign, clearSign, path, SignaturePath(armor, detachSign, clearSign, path))
+
+	return err
+}
+
+// SignFileTo signs the file at the given path like SignFile, but writes the
+// signature to the given output path. If the native backend is configured, the
+// file is signed in-process without calling the gpg binary. The metadata of the
+// created signature is returned, which is empty if gpg did not report it.
+func (c *Client) SignFileTo(
+	ctx context.Context, armor, detachSign, clearSign bool, path, output string,
+) (SignatureCreated, error) {
+	if c.Backend == BackendNative {
+		return c.signFileNative(ctx, armor, detachSign, clearSign, path, output)
+	}
+
 	args := []string{
 		"-u",
 		fmt.Sprintf("%s!", c.Key.Fingerprint),
 		"--batch",
 		"--no-tty",
+		"--status-fd",
+		statusFD,
 		"--yes",
 	}
 
@@ -25,10 +62,20 @@ func (c *Client) SignFile(armor, detachSign, clearSign bool, path string) error
 		args = append(args, "--armor")
 	}
 
-	if c.Key.Passphrase != "" {
+	// Freeze the clock of gpg to create reproducible signatures
+	if !c.SignatureTime.IsZero() {
+		args = append(args, "--faked-system-time", fmt.Sprintf("%d!", c.SignatureTime.Unix()))
+	}
+
+	// A preset passphrase is read from the cache of the gpg-agent
+	usePassphrase := c.Key.Passphrase != "" && !c.preset
+
+	if usePassphrase {
 		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
 	}
 
+	args = append(args, "--output", output)
+
 	switch {
 	case detachSign:
 		args = append(args, "--detach-sign")
@@ -40,24 +87,88 @@ func (c *Client) SignFile(armor, detachSign, clearSign bool, path string) error
 
 	args = append(args, path)
 
-	absBin, err := execabs.LookPath(c.gpgBin)
+	cmd, err := c.newCommand(ctx, c.gpgBin, args...)
 	if err != nil {
-		return fmt.Errorf("could not find executable %q: %w", c.gpgconfBin, err)
+		return SignatureCreated{}, err
 	}
 
-	cmd := plugin_exec.Command(absBin, args...)
 	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.TraceWriter = c.traceWriter
-	cmd.Env = append(cmd.Env, c.Env...)
 
-	if c.Key.Passphrase != "" {
+	if usePassphrase {
 		cmd.Stdin = strings.NewReader(c.Key.Passphrase)
 	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to sign file: %w", err)
+	statuses, err := cmd.runStatus()
+	if err != nil {
+		return SignatureCreated{}, fmt.Errorf("failed to sign file: %w", err)
+	}
+
+	for _, status := range statuses {
+		if status.Keyword != StatusSigCreated {
+			continue
+		}
+
+		sig, err := status.SignatureCreated()
+		if err != nil {
+			Logger(ctx).Debug().Str("file", path).Msgf("failed to parse signature status: %s", err)
+
+			continue
+		}
+
+		Logger(ctx).Debug().Str("file", path).Str("fingerprint", sig.Fingerprint).
+			Time("timestamp", sig.Timestamp).Msg("signature created")
+
+		return sig, nil
+	}
+
+	return SignatureCreated{}, nil
+}
+
+// CombineDetachedSignatures combines the given detached signature files into a single
+// signature file at the output path that contains all signature packets. The input
+// signatures may be binary or armored.
+func CombineDetachedSignatures(armored bool, output string, signatures ...string) error {
+	var packets bytes.Buffer
+
+	for _, path := range signatures {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCombineSignatures, err)
+		}
+
+		if bytes.HasPrefix(content, []byte(armorBeginPrefix)) {
+			block, err := armor.Decode(bytes.NewReader(content))
+			if err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrCombineSignatures, path, err)
+			}
+
+			if content, err = io.ReadAll(block.Body); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrCombineSignatures, path, err)
+			}
+		}
+
+		packets.Write(content)
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCombineSignatures, err)
+	}
+	defer out.Close()
+
+	if armored {
+		err = writeArmored(out, armorTypeSignature, func(w io.Writer) error {
+			_, err := w.Write(packets.Bytes())
+
+			return err
+		})
+	} else {
+		_, err = out.Write(packets.Bytes())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCombineSignatures, err)
 	}
 
-	return nil
+	return out.Close()
 }
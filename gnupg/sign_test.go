@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClient_SignFile(t *testing.T) {
@@ -32,9 +37,11 @@ func TestClient_SignFile(t *testing.T) {
 			bin:    os.Args[0],
 			env:    []string{"GO_TEST_MODE=pass"},
 			want: fmt.Sprintf(
-				"gnupg.test -u %s! --batch --no-tty --yes --pinentry-mode loopback --passphrase-fd 0 --sign %s",
+				"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --pinentry-mode loopback --passphrase-fd 0 "+
+					"--output %s.gpg --sign %s",
 				testKeyFingerprint,
 				testFile,
+				testFile,
 			),
 		},
 		{
@@ -46,9 +53,11 @@ func TestClient_SignFile(t *testing.T) {
 			bin:    os.Args[0],
 			env:    []string{"GO_TEST_MODE=pass"},
 			want: fmt.Sprintf(
-				"gnupg.test -u %s! --batch --no-tty --yes --armor --pinentry-mode loopback --passphrase-fd 0 --sign %s",
+				"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --armor --pinentry-mode loopback --passphrase-fd 0 "+
+					"--output %s.asc --sign %s",
 				testKeyFingerprint,
 				testFile,
+				testFile,
 			),
 		},
 		{
@@ -60,9 +69,11 @@ func TestClient_SignFile(t *testing.T) {
 			bin:    os.Args[0],
 			env:    []string{"GO_TEST_MODE=pass"},
 			want: fmt.Sprintf(
-				"gnupg.test -u %s! --batch --no-tty --yes --pinentry-mode loopback --passphrase-fd 0 --detach-sign %s",
+				"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --pinentry-mode loopback --passphrase-fd 0 "+
+					"--output %s.sig --detach-sign %s",
 				testKeyFingerprint,
 				testFile,
+				testFile,
 			),
 		},
 		{
@@ -74,9 +85,11 @@ func TestClient_SignFile(t *testing.T) {
 			bin:    os.Args[0],
 			env:    []string{"GO_TEST_MODE=pass"},
 			want: fmt.Sprintf(
-				"gnupg.test -u %s! --batch --no-tty --yes --pinentry-mode loopback --passphrase-fd 0 --clear-sign %s",
+				"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --pinentry-mode loopback --passphrase-fd 0 "+
+					"--output %s.asc --clear-sign %s",
 				testKeyFingerprint,
 				testFile,
+				testFile,
 			),
 		},
 		{
@@ -99,7 +112,7 @@ func TestClient_SignFile(t *testing.T) {
 				},
 			}
 
-			err := c.SignFile(tt.armor, tt.detach, tt.clear, tt.path)
+			err := c.SignFile(t.Context(), tt.armor, tt.detach, tt.clear, tt.path)
 
 			assert.Contains(t, buf.String(), tt.want)
 
@@ -113,3 +126,92 @@ func TestClient_SignFile(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_SignFileTo(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &Client{
+		gpgBin:      os.Args[0],
+		traceWriter: buf,
+		Env:         []string{"GO_TEST_MODE=pass"},
+		Key: Key{
+			Fingerprint: testKeyFingerprint,
+		},
+	}
+
+	_, err := c.SignFileTo(t.Context(), true, true, false, "/path/to/file", "/out/file.minisig-compatible.asc")
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), fmt.Sprintf(
+		"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --armor "+
+			"--output /out/file.minisig-compatible.asc --detach-sign /path/to/file",
+		testKeyFingerprint,
+	))
+}
+
+func TestClient_SignFileToSignatureTime(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &Client{
+		gpgBin:        os.Args[0],
+		traceWriter:   buf,
+		Env:           []string{"GO_TEST_MODE=pass"},
+		SignatureTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Key: Key{
+			Fingerprint: testKeyFingerprint,
+		},
+	}
+
+	_, err := c.SignFileTo(t.Context(), false, true, false, "/path/to/file", "/path/to/file.sig")
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), fmt.Sprintf(
+		"gnupg.test -u %s! --batch --no-tty --status-fd 3 --yes --faked-system-time 1704067200! "+
+			"--output /path/to/file.sig --detach-sign /path/to/file",
+		testKeyFingerprint,
+	))
+}
+
+func TestCombineDetachedSignatures(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0o600))
+
+	second, err := crypto.PGP().KeyGeneration().AddUserId("Jane Doe", "jane.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	secondArmored, err := second.Armor()
+	require.NoError(t, err)
+
+	keys := []Key{
+		{Content: testPrivateKey, Passphrase: testPassphrase, Fingerprint: testKeyFingerprint},
+		{Content: secondArmored, Fingerprint: strings.ToUpper(second.GetFingerprint())},
+	}
+
+	for _, armored := range []bool{false, true} {
+		t.Run(fmt.Sprintf("armor=%t", armored), func(t *testing.T) {
+			c := &Client{Backend: BackendNative}
+			signatures := make([]string, 0)
+
+			for i, key := range keys {
+				c.UseKey(key)
+
+				// Mix binary and armored input signatures.
+				sig := filepath.Join(dir, fmt.Sprintf("%d.sig", i))
+				_, err := c.SignFileTo(t.Context(), i == 0, true, false, path, sig)
+				require.NoError(t, err)
+
+				signatures = append(signatures, sig)
+			}
+
+			output := filepath.Join(dir, "file.txt.sig")
+			require.NoError(t, CombineDetachedSignatures(armored, output, signatures...))
+
+			content, err := os.ReadFile(output)
+			require.NoError(t, err)
+			assert.Equal(t, armored, strings.HasPrefix(string(content), armorBeginSignature))
+
+			// The combined signature must be valid for each of the keys.
+			for _, key := range keys {
+				c.UseKey(key)
+				assert.NoError(t, c.VerifySignedFileTo(true, false, path, output))
+			}
+		})
+	}
+}
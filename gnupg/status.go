@@ -0,0 +1,342 @@
+package gnupg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+var (
+	ErrBadPassphrase     = errors.New("bad passphrase")
+	ErrMissingPassphrase = errors.New("missing passphrase")
+	ErrNoSecretKey       = errors.New("secret key not available")
+	ErrInvalidSigner     = errors.New("invalid signer")
+	ErrImportProblem     = errors.New("key import problem")
+	ErrGPGFailure        = errors.New("gpg failure")
+	ErrInvalidStatus     = errors.New("invalid status line")
+)
+
+const (
+	statusPrefix = "[GNUPG:] "
+	// statusFD is the file descriptor of the first extra file of a command, which
+	// is used to read the status lines of gpg.
+	statusFD = "3"
+
+	StatusSigCreated        = "SIG_CREATED"
+	StatusImportOK          = "IMPORT_OK"
+	StatusImportProblem     = "IMPORT_PROBLEM"
+	StatusBadPassphrase     = "BAD_PASSPHRASE"
+	StatusMissingPassphrase = "MISSING_PASSPHRASE"
+	StatusNoSecretKey       = "NO_SECKEY"
+	StatusKeyExpired        = "KEYEXPIRED"
+	StatusKeyRevoked        = "KEYREVOKED"
+	StatusInvalidSigner     = "INV_SGNR"
+	StatusFailure           = "FAILURE"
+	StatusError             = "ERROR"
+
+	// Codes of libgpg-error used in FAILURE and ERROR status lines.
+	gpgErrBadPassphrase     = 11
+	gpgErrNoSecretKey       = 17
+	gpgErrUnusableSecretKey = 54
+	gpgErrNoPinentry        = 85
+	gpgErrCertRevoked       = 94
+	gpgErrWrongKeyUsage     = 125
+	gpgErrKeyExpired        = 153
+)
+
+// Status is a status line written by gpg to the file descriptor given with `--status-fd`.
+type Status struct {
+	Keyword string
+	Args    []string
+}
+
+// SignatureCreated is the result of a SIG_CREATED status line.
+type SignatureCreated struct {
+	Type        SignatureType
+	PubKeyAlgo  packet.PublicKeyAlgorithm
+	HashAlgo    int
+	Class       string
+	Timestamp   time.Time
+	Fingerprint string
+}
+
+// ImportOK is the result of an IMPORT_OK status line.
+type ImportOK struct {
+	Reason      int
+	Fingerprint string
+}
+
+// ParseStatus reads the status lines from the given reader. Lines without the status
+// prefix are ignored.
+func ParseStatus(r io.Reader) ([]Status, error) {
+	var statuses []Status
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), statusPrefix)
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		statuses = append(statuses, Status{Keyword: fields[0], Args: fields[1:]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return statuses, fmt.Errorf("failed to read status: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// SignatureCreated parses a SIG_CREATED status line.
+func (s Status) SignatureCreated() (SignatureCreated, error) {
+	var sig SignatureCreated
+
+	if s.Keyword != StatusSigCreated || len(s.Args) < 6 {
+		return sig, fmt.Errorf("%w: %s", ErrInvalidStatus, s)
+	}
+
+	switch s.Args[0] {
+	case "D":
+		sig.Type = SignatureDetached
+	case "C":
+		sig.Type = SignatureCleartext
+	default:
+		sig.Type = SignatureInline
+	}
+
+	pubKeyAlgo, err := strconv.Atoi(s.Args[1])
+	if err != nil {
+		return sig, fmt.Errorf("%w: %s: %w", ErrInvalidStatus, s, err)
+	}
+
+	hashAlgo, err := strconv.Atoi(s.Args[2])
+	if err != nil {
+		return sig, fmt.Errorf("%w: %s: %w", ErrInvalidStatus, s, err)
+	}
+
+	timestamp, err := parseStatusTime(s.Args[4])
+	if err != nil {
+		return sig, fmt.Errorf("%w: %s: %w", ErrInvalidStatus, s, err)
+	}
+
+	sig.PubKeyAlgo = packet.PublicKeyAlgorithm(pubKeyAlgo)
+	sig.HashAlgo = hashAlgo
+	sig.Class = s.Args[3]
+	sig.Timestamp = timestamp
+	sig.Fingerprint = s.Args[5]
+
+	return sig, nil
+}
+
+// ImportOK parses an IMPORT_OK status line.
+func (s Status) ImportOK() (ImportOK, error) {
+	var res ImportOK
+
+	if s.Keyword != StatusImportOK || len(s.Args) < 2 {
+		return res, fmt.Errorf("%w: %s", ErrInvalidStatus, s)
+	}
+
+	reason, err := strconv.Atoi(s.Args[0])
+	if err != nil {
+		return res, fmt.Errorf("%w: %s: %w", ErrInvalidStatus, s, err)
+	}
+
+	res.Reason = reason
+	res.Fingerprint = s.Args[1]
+
+	return res, nil
+}
+
+// Err returns the typed error of the status line, or nil if the status does not
+// describe a failure.
+func (s Status) Err() error {
+	switch s.Keyword {
+	case StatusBadPassphrase:
+		return fmt.Errorf("%w for key %s", ErrBadPassphrase, s.arg(0))
+	case StatusMissingPassphrase:
+		return ErrMissingPassphrase
+	case StatusNoSecretKey:
+		return fmt.Errorf("%w: %s", ErrNoSecretKey, s.arg(0))
+	case StatusKeyExpired:
+		if expired, err := parseStatusTime(s.arg(0)); err == nil {
+			return fmt.Errorf("%w: since %s", ErrKeyExpired, expired.Format(time.RFC3339))
+		}
+
+		return ErrKeyExpired
+	case StatusKeyRevoked:
+		return ErrKeyRevoked
+	case StatusInvalidSigner:
+		return invalidSignerError(s.arg(0), s.arg(1))
+	case StatusImportProblem:
+		return fmt.Errorf("%w: %s: %s", ErrImportProblem, s.arg(1), importProblemReason(s.arg(0)))
+	case StatusFailure, StatusError:
+		return failureError(s.arg(0), s.arg(1))
+	}
+
+	return nil
+}
+
+// arg returns the argument at the given index, or an empty string if it is missing.
+func (s Status) arg(i int) string {
+	if i < len(s.Args) {
+		return s.Args[i]
+	}
+
+	return ""
+}
+
+func (s Status) String() string {
+	return strings.TrimSpace(s.Keyword + " " + strings.Join(s.Args, " "))
+}
+
+// statusError returns the typed errors of the given status lines. As gpg reports
+// expired and revoked keys also for keys that are not used, these errors are only
+// returned if there is no more specific error. Failures with an unknown error code
+// are only returned if there is no other error at all.
+func statusError(statuses []Status) error {
+	var errs, keyErrs, failures []error
+
+	for _, status := range statuses {
+		err := status.Err()
+
+		switch {
+		case err == nil:
+			continue
+		case status.Keyword == StatusKeyExpired, status.Keyword == StatusKeyRevoked:
+			keyErrs = appendUniqueError(keyErrs, err)
+		case errors.Is(err, ErrGPGFailure) && failureCause(status.arg(1)) == nil:
+			failures = appendUniqueError(failures, err)
+		default:
+			errs = appendUniqueError(errs, err)
+		}
+	}
+
+	switch {
+	case len(errs) > 0:
+		return errors.Join(errs...)
+	case len(keyErrs) > 0:
+		return errors.Join(keyErrs...)
+	}
+
+	return errors.Join(failures...)
+}
+
+func appendUniqueError(errs []error, err error) []error {
+	for _, e := range errs {
+		if e.Error() == err.Error() {
+			return errs
+		}
+	}
+
+	return append(errs, err)
+}
+
+// invalidSignerError returns the error of an INV_SGNR status line with the given
+// reason code. Known reasons are wrapped into the matching key error.
+func invalidSignerError(code, key string) error {
+	switch code {
+	case "3":
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSigner, key, ErrKeyCannotSign)
+	case "4":
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSigner, key, ErrKeyRevoked)
+	case "5":
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSigner, key, ErrKeyExpired)
+	case "9":
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSigner, key, ErrNoSecretKey)
+	}
+
+	return fmt.Errorf("%w: %s: %s", ErrInvalidSigner, key, invalidSignerReason(code))
+}
+
+// failureError returns the error of a FAILURE or ERROR status line with the given
+// location and gpg-error code. Known error codes are wrapped into the matching error.
+func failureError(location, code string) error {
+	if cause := failureCause(code); cause != nil {
+		return fmt.Errorf("%w: %s: %w", ErrGPGFailure, location, cause)
+	}
+
+	return fmt.Errorf("%w: %s: code %s", ErrGPGFailure, location, code)
+}
+
+// failureCause returns the error matching the given gpg-error code, or nil if the
+// code is unknown.
+func failureCause(code string) error {
+	value, err := strconv.ParseUint(code, 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	// The error code is encoded in the lower 16 bits, the upper bits contain the error source
+	switch value & 0xffff {
+	case gpgErrBadPassphrase:
+		return ErrBadPassphrase
+	case gpgErrNoSecretKey:
+		return ErrNoSecretKey
+	case gpgErrNoPinentry:
+		return ErrMissingPassphrase
+	case gpgErrUnusableSecretKey, gpgErrWrongKeyUsage:
+		return ErrKeyCannotSign
+	case gpgErrKeyExpired:
+		return ErrKeyExpired
+	case gpgErrCertRevoked:
+		return ErrKeyRevoked
+	}
+
+	return nil
+}
+
+func invalidSignerReason(code string) string {
+	switch code {
+	case "1":
+		return "not found"
+	case "2":
+		return "ambiguous specification"
+	case "8":
+		return "policy mismatch"
+	case "10":
+		return "key not trusted"
+	case "13":
+		return "key disabled"
+	case "14":
+		return "syntax error in specification"
+	}
+
+	return "reason code " + code
+}
+
+func importProblemReason(code string) string {
+	switch code {
+	case "1":
+		return "invalid certificate"
+	case "2":
+		return "issuer certificate missing"
+	case "3":
+		return "certificate chain too long"
+	case "4":
+		return "error storing certificate"
+	}
+
+	return "no specific reason"
+}
+
+// parseStatusTime parses a timestamp of a status line, which is either given in
+// seconds since epoch or in ISO 8601 format.
+func parseStatusTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	return time.Parse("20060102T150405", value)
+}
@@ -0,0 +1,227 @@
+package gnupg
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatus(t *testing.T) {
+	input := "[GNUPG:] KEY_CONSIDERED AB2EA2158A1B650CCDED7BAF088E8C12D831B31B 2\n" +
+		"gpg: using key\n" +
+		"[GNUPG:] BEGIN_SIGNING H8\n" +
+		"[GNUPG:] SIG_CREATED D 1 8 00 1704067200 AB2EA2158A1B650CCDED7BAF088E8C12D831B31B\n"
+
+	statuses, err := ParseStatus(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Status{
+		{Keyword: "KEY_CONSIDERED", Args: []string{"AB2EA2158A1B650CCDED7BAF088E8C12D831B31B", "2"}},
+		{Keyword: "BEGIN_SIGNING", Args: []string{"H8"}},
+		{Keyword: "SIG_CREATED", Args: []string{"D", "1", "8", "00", "1704067200", testKeyFingerprint}},
+	}, statuses)
+
+	sig, err := statuses[2].SignatureCreated()
+	require.NoError(t, err)
+	assert.Equal(t, SignatureCreated{
+		Type:        SignatureDetached,
+		PubKeyAlgo:  packet.PubKeyAlgoRSA,
+		HashAlgo:    8,
+		Class:       "00",
+		Timestamp:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Fingerprint: testKeyFingerprint,
+	}, sig)
+
+	_, err = statuses[1].SignatureCreated()
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestStatus_ImportOK(t *testing.T) {
+	res, err := Status{Keyword: StatusImportOK, Args: []string{"17", testKeyFingerprint}}.ImportOK()
+	require.NoError(t, err)
+	assert.Equal(t, ImportOK{Reason: 17, Fingerprint: testKeyFingerprint}, res)
+
+	_, err = Status{Keyword: StatusImportOK, Args: []string{"invalid", testKeyFingerprint}}.ImportOK()
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  []error
+		wantMsg  string
+	}{
+		{
+			name:     "no error",
+			statuses: []string{"SIG_CREATED D 1 8 00 1704067200 " + testKeyFingerprint},
+		},
+		{
+			name:     "bad passphrase",
+			statuses: []string{"BAD_PASSPHRASE 088E8C12D831B31B"},
+			wantErr:  []error{ErrBadPassphrase},
+			wantMsg:  "bad passphrase for key 088E8C12D831B31B",
+		},
+		{
+			name:     "bad passphrase from failure code",
+			statuses: []string{"FAILURE sign 67108875"},
+			wantErr:  []error{ErrGPGFailure, ErrBadPassphrase},
+		},
+		{
+			name:     "missing passphrase",
+			statuses: []string{"MISSING_PASSPHRASE"},
+			wantErr:  []error{ErrMissingPassphrase},
+		},
+		{
+			name:     "no secret key",
+			statuses: []string{"NO_SECKEY 088E8C12D831B31B", "FAILURE sign 17"},
+			wantErr:  []error{ErrNoSecretKey},
+			wantMsg:  "secret key not available: 088E8C12D831B31B\ngpg failure: sign: secret key not available",
+		},
+		{
+			name:     "expired key",
+			statuses: []string{"KEYEXPIRED 1704067200", "FAILURE sign 83886233"},
+			wantErr:  []error{ErrKeyExpired},
+		},
+		{
+			name:     "expired key reported for other subkey",
+			statuses: []string{"KEYEXPIRED 1704067200", "FAILURE sign 67108875"},
+			wantErr:  []error{ErrBadPassphrase},
+			wantMsg:  "gpg failure: sign: bad passphrase",
+		},
+		{
+			name:     "revoked key",
+			statuses: []string{"KEYREVOKED"},
+			wantErr:  []error{ErrKeyRevoked},
+		},
+		{
+			name:     "invalid signer expired",
+			statuses: []string{"INV_SGNR 5 " + testKeyFingerprint, "FAILURE sign 53"},
+			wantErr:  []error{ErrInvalidSigner, ErrKeyExpired},
+		},
+		{
+			name:     "invalid signer not found",
+			statuses: []string{"INV_SGNR 1 0000000000000000", "FAILURE sign 53"},
+			wantErr:  []error{ErrInvalidSigner},
+			wantMsg:  "invalid signer: 0000000000000000: not found",
+		},
+		{
+			name:     "import problem",
+			statuses: []string{"IMPORT_PROBLEM 1 " + testKeyFingerprint},
+			wantErr:  []error{ErrImportProblem},
+		},
+		{
+			name:     "unknown failure",
+			statuses: []string{"FAILURE sign 53"},
+			wantErr:  []error{ErrGPGFailure},
+			wantMsg:  "gpg failure: sign: code 53",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses, err := ParseStatus(strings.NewReader(statusLines(tt.statuses...)))
+			require.NoError(t, err)
+
+			err = statusError(statuses)
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			for _, want := range tt.wantErr {
+				assert.ErrorIs(t, err, want)
+			}
+
+			if tt.wantMsg != "" {
+				assert.EqualError(t, err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestClient_SignFileToStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		wantErr error
+	}{
+		{
+			name:    "bad passphrase",
+			status:  "BAD_PASSPHRASE 088E8C12D831B31B",
+			wantErr: ErrBadPassphrase,
+		},
+		{
+			name:    "invalid signer",
+			status:  "INV_SGNR 9 " + testKeyFingerprint,
+			wantErr: ErrNoSecretKey,
+		},
+		{
+			name:   "no status",
+			status: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				gpgBin: os.Args[0],
+				Env:    []string{"GO_TEST_MODE=status", "GO_TEST_STATUS=" + statusLines(tt.status)},
+				Key: Key{
+					Fingerprint: testKeyFingerprint,
+					Passphrase:  testPassphrase,
+				},
+			}
+
+			_, err := c.SignFileTo(t.Context(), false, true, false, "/path/to/file", "/path/to/file.sig")
+			assert.Error(t, err)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_SignFileToSignatureCreated(t *testing.T) {
+	c := &Client{
+		gpgBin: os.Args[0],
+		Env: []string{
+			"GO_TEST_MODE=status ok",
+			"GO_TEST_STATUS=" + statusLines(
+				"KEY_CONSIDERED "+testKeyFingerprint+" 0",
+				"SIG_CREATED D 1 8 00 1710151543 "+testKeyFingerprint,
+			),
+		},
+		Key: Key{Fingerprint: testKeyFingerprint},
+	}
+
+	sig, err := c.SignFileTo(t.Context(), false, true, false, "/path/to/file", "/path/to/file.sig")
+	require.NoError(t, err)
+	assert.Equal(t, SignatureCreated{
+		Type:        SignatureDetached,
+		PubKeyAlgo:  packet.PubKeyAlgoRSA,
+		HashAlgo:    8,
+		Class:       "00",
+		Timestamp:   time.Unix(1710151543, 0).UTC(),
+		Fingerprint: testKeyFingerprint,
+	}, sig)
+}
+
+func statusLines(lines ...string) string {
+	var b strings.Builder
+
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(statusPrefix + line + "\n")
+		}
+	}
+
+	return b.String()
+}
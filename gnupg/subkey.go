@@ -0,0 +1,251 @@
+package gnupg
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+)
+
+var (
+	ErrFingerprintNotFound = errors.New("fingerprint does not belong to the key")
+	ErrKeyCannotSign       = errors.New("key can not be used for signing")
+	ErrNoSigningKey        = errors.New("no valid signing key found")
+)
+
+// Subkey holds the metadata of the primary key or a subkey.
+type Subkey struct {
+	Fingerprint    string
+	KeyID          string
+	Algorithm      string
+	BitLength      int
+	Curve          string
+	Primary        bool
+	CreationTime   time.Time
+	ExpirationTime time.Time
+
+	CanSign         bool
+	CanCertify      bool
+	CanEncrypt      bool
+	CanAuthenticate bool
+
+	Expired     bool
+	Revoked     bool
+	NotYetValid bool
+}
+
+// Capabilities returns the capabilities of the key in the gpg notation, e.g. `SC`.
+func (s Subkey) Capabilities() string {
+	var caps strings.Builder
+
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"S", s.CanSign},
+		{"C", s.CanCertify},
+		{"E", s.CanEncrypt},
+		{"A", s.CanAuthenticate},
+	} {
+		if flag.set {
+			caps.WriteString(flag.name)
+		}
+	}
+
+	return caps.String()
+}
+
+// State returns a short description of the validity of the key.
+func (s Subkey) State() string {
+	switch {
+	case s.Revoked:
+		return "revoked"
+	case s.Expired:
+		return "expired"
+	case s.NotYetValid:
+		return "not yet valid"
+	}
+
+	return "valid"
+}
+
+// SigningSubkey returns the primary key or subkey matching the given fingerprint.
+// It returns an error if the fingerprint does not belong to the key, or the key
+// is not capable of signing, expired or revoked.
+func (k Key) SigningSubkey(fingerprint string) (Subkey, error) {
+	for _, subkey := range k.Subkeys {
+		if !strings.EqualFold(subkey.Fingerprint, fingerprint) {
+			continue
+		}
+
+		if err := k.usableForSigning(subkey); err != nil {
+			return subkey, err
+		}
+
+		return subkey, nil
+	}
+
+	return Subkey{}, fmt.Errorf("%w: %s", ErrFingerprintNotFound, fingerprint)
+}
+
+// NewestSigningSubkey returns the most recently created valid signing subkey.
+// The primary key is only returned if no signing subkey is available. If no key is
+// valid, the reasons of all keys capable of signing are returned.
+func (k Key) NewestSigningSubkey() (Subkey, error) {
+	var (
+		newest Subkey
+		found  bool
+		errs   []error
+	)
+
+	for _, subkey := range k.Subkeys {
+		if subkey.Primary {
+			continue
+		}
+
+		if err := k.usableForSigning(subkey); err != nil {
+			if subkey.CanSign {
+				errs = appendUniqueError(errs, err)
+			}
+
+			continue
+		}
+
+		if !found || subkey.CreationTime.After(newest.CreationTime) {
+			newest, found = subkey, true
+		}
+	}
+
+	if found {
+		return newest, nil
+	}
+
+	for _, subkey := range k.Subkeys {
+		if !subkey.Primary {
+			continue
+		}
+
+		err := k.usableForSigning(subkey)
+		if err == nil {
+			return subkey, nil
+		}
+
+		if subkey.CanSign {
+			errs = appendUniqueError(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return Subkey{}, fmt.Errorf("%w: %w", ErrNoSigningKey, errors.Join(errs...))
+	}
+
+	return Subkey{}, ErrNoSigningKey
+}
+
+func (k Key) usableForSigning(subkey Subkey) error {
+	for _, key := range k.Subkeys {
+		if !key.Primary && key.Fingerprint != subkey.Fingerprint {
+			continue
+		}
+
+		switch {
+		case key.Revoked:
+			return fmt.Errorf("%w: %s: %w", ErrKeyCannotSign, key.Fingerprint, ErrKeyRevoked)
+		case key.Expired:
+			return fmt.Errorf("%w: %s: %w", ErrKeyCannotSign, key.Fingerprint, ErrKeyExpired)
+		case key.NotYetValid:
+			return fmt.Errorf("%w: %s: %w: %s", ErrKeyCannotSign, key.Fingerprint, ErrKeyNotYetValid, key.CreationTime)
+		}
+	}
+
+	if !subkey.CanSign {
+		return fmt.Errorf("%w: %s: missing sign capability", ErrKeyCannotSign, subkey.Fingerprint)
+	}
+
+	return nil
+}
+
+// readSubkeys returns the metadata of the primary key followed by all subkeys of the entity.
+// Expiry and revocation are evaluated at the given current time, while keys created after
+// the given signature time are marked as not yet valid.
+func readSubkeys(entity *openpgp.Entity, now, signedAt time.Time) []Subkey {
+	subkeys := make([]Subkey, 0, len(entity.Subkeys)+1)
+
+	primary := newSubkey(entity.PrimaryKey)
+	primary.Primary = true
+	primary.Revoked = entity.Revoked(now)
+
+	if sig, err := entity.PrimarySelfSignature(time.Time{}, nil); err == nil {
+		primary.setSelfSignature(entity.PrimaryKey, sig, now, signedAt)
+	}
+
+	subkeys = append(subkeys, primary)
+
+	for i := range entity.Subkeys {
+		sk := &entity.Subkeys[i]
+		subkey := newSubkey(sk.PublicKey)
+
+		sig, err := sk.LatestValidBindingSignature(time.Time{}, nil)
+		if err != nil {
+			// A subkey without valid binding signature can not be used at all.
+			subkey.Revoked = true
+			subkeys = append(subkeys, subkey)
+
+			continue
+		}
+
+		subkey.setSelfSignature(sk.PublicKey, sig, now, signedAt)
+		subkey.Revoked = sk.Revoked(sig, now)
+
+		subkeys = append(subkeys, subkey)
+	}
+
+	return subkeys
+}
+
+func newSubkey(pk *packet.PublicKey) Subkey {
+	subkey := Subkey{
+		Fingerprint:  strings.ToUpper(hex.EncodeToString(pk.Fingerprint)),
+		KeyID:        pk.KeyIdString(),
+		Algorithm:    AlgorithmName(pk.PubKeyAlgo),
+		CreationTime: pk.CreationTime.UTC(),
+	}
+
+	if bits, err := pk.BitLength(); err == nil {
+		subkey.BitLength = int(bits)
+	}
+
+	if curve, err := pk.Curve(); err == nil {
+		subkey.Curve = string(curve)
+	}
+
+	return subkey
+}
+
+// setSelfSignature reads the capabilities and expiration of the key from its
+// self-signature. Keys without key flags get the default capabilities of their
+// algorithm.
+func (s *Subkey) setSelfSignature(pk *packet.PublicKey, sig *packet.Signature, now, signedAt time.Time) {
+	if sig.FlagsValid {
+		s.CanSign = sig.FlagSign
+		s.CanCertify = sig.FlagCertify
+		s.CanEncrypt = sig.FlagEncryptCommunications || sig.FlagEncryptStorage
+		s.CanAuthenticate = sig.FlagAuthenticate
+	} else {
+		s.CanSign = pk.PubKeyAlgo.CanSign()
+		s.CanCertify = s.Primary && s.CanSign
+		s.CanEncrypt = pk.PubKeyAlgo.CanEncrypt()
+	}
+
+	if sig.KeyLifetimeSecs != nil && *sig.KeyLifetimeSecs != 0 {
+		s.ExpirationTime = pk.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second).UTC()
+	}
+
+	// A key is not expired before it was created, go-crypto reports it as expired though
+	s.NotYetValid = pk.CreationTime.After(signedAt)
+	s.Expired = !pk.CreationTime.After(now) && (pk.KeyExpired(sig, now) || sig.SigExpired(now))
+}
@@ -0,0 +1,186 @@
+package gnupg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSubkeys(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("John Doe", "john.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	entity := key.GetEntity()
+	now := time.Now()
+
+	require.NoError(t, entity.AddSigningSubkey(&packet.Config{Time: func() time.Time { return now.Add(time.Minute) }}))
+	require.NoError(t, entity.AddSigningSubkey(&packet.Config{
+		Time:            func() time.Time { return now.Add(-time.Hour) },
+		KeyLifetimeSecs: 60,
+	}))
+
+	subkeys := readSubkeys(entity, now.Add(2*time.Minute), now.Add(2*time.Minute))
+	require.Len(t, subkeys, 4)
+
+	assert.True(t, subkeys[0].Primary)
+	assert.Equal(t, strings.ToUpper(key.GetFingerprint()), subkeys[0].Fingerprint)
+	assert.Equal(t, "SC", subkeys[0].Capabilities())
+	assert.Equal(t, "valid", subkeys[0].State())
+
+	assert.False(t, subkeys[1].Primary)
+	assert.Equal(t, "E", subkeys[1].Capabilities())
+
+	assert.Equal(t, "S", subkeys[2].Capabilities())
+	assert.Equal(t, "valid", subkeys[2].State())
+	assert.True(t, subkeys[2].ExpirationTime.IsZero())
+
+	assert.Equal(t, "S", subkeys[3].Capabilities())
+	assert.Equal(t, "expired", subkeys[3].State())
+	assert.Equal(t, subkeys[3].CreationTime.Add(time.Minute), subkeys[3].ExpirationTime)
+
+	got, err := (Key{Subkeys: subkeys}).NewestSigningSubkey()
+	assert.NoError(t, err)
+	assert.Equal(t, subkeys[2].Fingerprint, got.Fingerprint)
+
+	// Subkeys created after the given time are not yet valid
+	subkeys = readSubkeys(entity, now.Add(2*time.Minute), now.Add(30*time.Second))
+	assert.Equal(t, "not yet valid", subkeys[2].State())
+
+	got, err = (Key{Subkeys: subkeys}).NewestSigningSubkey()
+	assert.NoError(t, err)
+	assert.True(t, got.Primary)
+
+	_, err = (Key{Subkeys: readSubkeys(entity, now, now.Add(-2*time.Hour))}).NewestSigningSubkey()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+	assert.ErrorIs(t, err, ErrKeyNotYetValid)
+}
+
+func TestKey_SigningSubkey(t *testing.T) {
+	key := Key{
+		Subkeys: []Subkey{
+			{Fingerprint: "AAAA", Primary: true, CanSign: true, CanCertify: true},
+			{Fingerprint: "BBBB", CanEncrypt: true},
+			{Fingerprint: "CCCC", CanSign: true},
+			{Fingerprint: "DDDD", CanSign: true, Revoked: true},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		key         Key
+		fingerprint string
+		wantErr     error
+	}{
+		{
+			name:        "primary key",
+			key:         key,
+			fingerprint: "AAAA",
+		},
+		{
+			name:        "signing subkey",
+			key:         key,
+			fingerprint: "cccc",
+		},
+		{
+			name:        "encryption subkey",
+			key:         key,
+			fingerprint: "BBBB",
+			wantErr:     ErrKeyCannotSign,
+		},
+		{
+			name:        "revoked subkey",
+			key:         key,
+			fingerprint: "DDDD",
+			wantErr:     ErrKeyCannotSign,
+		},
+		{
+			name: "expired primary key",
+			key: Key{
+				Subkeys: []Subkey{
+					{Fingerprint: "AAAA", Primary: true, CanSign: true, Expired: true},
+					{Fingerprint: "CCCC", CanSign: true},
+				},
+			},
+			fingerprint: "CCCC",
+			wantErr:     ErrKeyCannotSign,
+		},
+		{
+			name:        "unknown fingerprint",
+			key:         key,
+			fingerprint: "EEEE",
+			wantErr:     ErrFingerprintNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.key.SigningSubkey(tt.fingerprint)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ToUpper(tt.fingerprint), got.Fingerprint)
+		})
+	}
+}
+
+func TestKey_NewestSigningSubkey(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		subkeys []Subkey
+		want    string
+		wantErr error
+	}{
+		{
+			name: "newest signing subkey",
+			subkeys: []Subkey{
+				{Fingerprint: "AAAA", Primary: true, CanSign: true, CreationTime: created},
+				{Fingerprint: "BBBB", CanSign: true, CreationTime: created.Add(time.Hour)},
+				{Fingerprint: "CCCC", CanSign: true, CreationTime: created.Add(2 * time.Hour)},
+				{Fingerprint: "DDDD", CanSign: true, CreationTime: created.Add(3 * time.Hour), Expired: true},
+				{Fingerprint: "EEEE", CanEncrypt: true, CreationTime: created.Add(4 * time.Hour)},
+			},
+			want: "CCCC",
+		},
+		{
+			name: "fallback to primary key",
+			subkeys: []Subkey{
+				{Fingerprint: "AAAA", Primary: true, CanSign: true, CreationTime: created},
+				{Fingerprint: "BBBB", CanEncrypt: true, CreationTime: created.Add(time.Hour)},
+			},
+			want: "AAAA",
+		},
+		{
+			name: "no signing key",
+			subkeys: []Subkey{
+				{Fingerprint: "AAAA", Primary: true, CanCertify: true, CreationTime: created},
+				{Fingerprint: "BBBB", CanSign: true, Revoked: true, CreationTime: created.Add(time.Hour)},
+			},
+			wantErr: ErrNoSigningKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Key{Subkeys: tt.subkeys}.NewestSigningSubkey()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Fingerprint)
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package gnupg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+	"github.com/ProtonMail/gopenpgp/v3/armor"
+	"github.com/ProtonMail/gopenpgp/v3/constants"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+)
+
+var (
+	ErrReadPublicKeyFailed = errors.New("failed to read public key")
+	ErrNoPublicKeys        = errors.New("no public keys found")
+	ErrSignatureMissing    = errors.New("signature missing")
+	ErrSignatureBad        = errors.New("bad signature")
+	ErrSignatureExpired    = errors.New("signature or key expired")
+	ErrUnknownSigner       = errors.New("signed by unknown key")
+	ErrUnexpectedSigner    = errors.New("signed by unexpected fingerprint")
+	ErrVerifySignedFile    = errors.New("failed to verify signature")
+)
+
+const (
+	armorBeginPrefix    = "-----BEGIN PGP "
+	armorBeginSignature = "-----BEGIN PGP SIGNATURE-----"
+	armorBeginMessage   = "-----BEGIN PGP MESSAGE-----"
+	armorBeginSigned    = "-----BEGIN PGP SIGNED MESSAGE-----"
+
+	headSize = 64
+)
+
+type SignatureType string
+
+const (
+	SignatureDetached  SignatureType = "detached"
+	SignatureCleartext SignatureType = "cleartext"
+	SignatureInline    SignatureType = "inline"
+)
+
+// Verification holds the result of a signature verification.
+type Verification struct {
+	Path          string
+	SignaturePath string
+	Type          SignatureType
+	Fingerprint   string
+	KeyID         string
+	CreationTime  time.Time
+	Err           error
+}
+
+// ReadPublicKeys reads one or more public keys into the keyring of the client. The
+// content may contain multiple concatenated armor blocks. Content without armor block
+// is read as binary keyring, e.g. a base64 decoded key. Private keys are converted to
+// their public part.
+func (c *Client) ReadPublicKeys(content string) error {
+	keyring, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+	}
+
+	blocks := make([][]byte, 0)
+
+	for _, block := range splitArmorBlocks(content) {
+		data, err := armor.Unarmor(block)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+		}
+
+		blocks = append(blocks, data)
+	}
+
+	if len(blocks) == 0 && strings.TrimSpace(content) != "" {
+		blocks = append(blocks, []byte(content))
+	}
+
+	for _, data := range blocks {
+		entities, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+		}
+
+		for _, entity := range entities {
+			key, err := crypto.NewKeyFromEntity(entity)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+			}
+
+			if key.IsPrivate() {
+				if key, err = key.ToPublic(); err != nil {
+					return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+				}
+			}
+
+			if err := keyring.AddKey(key); err != nil {
+				return fmt.Errorf("%w: %w", ErrReadPublicKeyFailed, err)
+			}
+		}
+	}
+
+	if keyring.CountEntities() == 0 {
+		return ErrNoPublicKeys
+	}
+
+	c.keyring = keyring
+
+	return nil
+}
+
+// PublicKeys returns the fingerprints of the primary keys in the keyring of the client.
+func (c *Client) PublicKeys() []string {
+	result := make([]string, 0)
+
+	if c.keyring == nil {
+		return result
+	}
+
+	for _, key := range c.keyring.GetKeys() {
+		result = append(result, strings.ToUpper(key.GetFingerprint()))
+	}
+
+	return result
+}
+
+// VerifyFile verifies the signature of the file at the given path against the keyring
+// of the client. The path can either point to a cleartext or inline signed file, to a
+// detached signature or to a file with a detached `.sig` or `.asc` signature next to it.
+// If fingerprints are given, the signature must be made by one of them. The fingerprint
+// can either be the fingerprint of the primary key or the signing subkey.
+func (c *Client) VerifyFile(path string, fingerprints []string) *Verification {
+	v := &Verification{Path: path}
+
+	if c.keyring == nil {
+		v.Err = ErrNoPublicKeys
+
+		return v
+	}
+
+	head, err := readHead(path)
+	if err != nil {
+		v.Err = err
+
+		return v
+	}
+
+	ext := filepath.Ext(path)
+
+	switch {
+	case bytes.HasPrefix(head, []byte(armorBeginSigned)):
+		v.Type = SignatureCleartext
+		v.SignaturePath = path
+	case bytes.HasPrefix(head, []byte(armorBeginMessage)) || ext == ".gpg":
+		v.Type = SignatureInline
+		v.SignaturePath = path
+	case bytes.HasPrefix(head, []byte(armorBeginSignature)) || ext == ".sig":
+		v.Type = SignatureDetached
+		v.Path = strings.TrimSuffix(path, ext)
+		v.SignaturePath = path
+	default:
+		v.Type = SignatureDetached
+
+		for _, sigExt := range []string{".sig", ".asc"} {
+			if _, err := os.Stat(path + sigExt); err == nil {
+				v.SignaturePath = path + sigExt
+
+				break
+			}
+		}
+
+		if v.SignaturePath == "" {
+			v.Err = ErrSignatureMissing
+
+			return v
+		}
+	}
+
+	res, err := c.verify(v)
+	if err != nil {
+		v.Err = err
+
+		return v
+	}
+
+	v.Err = verifyResultError(res, fingerprints)
+
+	if res.SignedByFingerprint() != nil {
+		v.Fingerprint = strings.ToUpper(hex.EncodeToString(res.SignedByFingerprint()))
+		v.KeyID = strings.ToUpper(res.SignedByKeyIdHex())
+		v.CreationTime = time.Unix(res.SignatureCreationTime(), 0).UTC()
+	}
+
+	return v
+}
+
+// VerifySignedFileTo verifies the signature that SignFileTo created for the given path
+// at the given output path with the same flags. The signature must be valid for the
+// public part of the private key and must be made by the key matching Key.Fingerprint.
+// The armor flag is not required as the encoding is detected automatically.
+func (c *Client) VerifySignedFileTo(detachSign, clearSign bool, path, output string) error {
+	if c.keyring == nil {
+		if err := c.ReadPublicKeys(c.Key.Content); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrVerifySignedFile, path, err)
+		}
+	}
+
+	v := &Verification{Path: path, SignaturePath: output}
+
+	switch {
+	case detachSign:
+		v.Type = SignatureDetached
+	case clearSign:
+		v.Type = SignatureCleartext
+	default:
+		v.Type = SignatureInline
+	}
+
+	res, err := c.verify(v)
+	if err == nil {
+		err = verifyResultError(res, nil)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrVerifySignedFile, path, err)
+	}
+
+	fingerprint := strings.ToUpper(hex.EncodeToString(res.SignedByFingerprint()))
+	if c.Key.Fingerprint != "" && !strings.EqualFold(fingerprint, c.Key.Fingerprint) {
+		return fmt.Errorf("%w: %s: %w: %s", ErrVerifySignedFile, path, ErrUnexpectedSigner, fingerprint)
+	}
+
+	return nil
+}
+
+func (c *Client) verify(v *Verification) (*crypto.VerifyResult, error) {
+	verifier, err := crypto.PGP().Verify().VerificationKeys(c.keyring).New()
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := os.Open(v.SignaturePath)
+	if err != nil {
+		return nil, err
+	}
+	defer sig.Close()
+
+	switch v.Type {
+	case SignatureCleartext:
+		content, err := io.ReadAll(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := verifier.VerifyCleartext(content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSignatureBad, err)
+		}
+
+		return &res.VerifyResult, nil
+	case SignatureInline:
+		reader, err := verifier.VerifyingReader(nil, sig, crypto.Auto)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSignatureBad, err)
+		}
+
+		return reader.DiscardAllAndVerifySignature()
+	default:
+		data, err := os.Open(v.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer data.Close()
+
+		reader, err := verifier.VerifyingReader(data, sig, crypto.Auto)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSignatureBad, err)
+		}
+
+		return reader.DiscardAllAndVerifySignature()
+	}
+}
+
+// verifyResultError maps the result of a signature verification to an error.
+func verifyResultError(res *crypto.VerifyResult, fingerprints []string) error {
+	if err := res.SignatureError(); err != nil {
+		var sigErr crypto.SignatureVerificationError
+		if !errors.As(err, &sigErr) {
+			return fmt.Errorf("%w: %w", ErrSignatureBad, err)
+		}
+
+		switch {
+		case sigErr.Status == constants.SIGNATURE_NOT_SIGNED:
+			return ErrSignatureMissing
+		case sigErr.Status == constants.SIGNATURE_NO_VERIFIER:
+			return fmt.Errorf("%w: %s", ErrUnknownSigner, strings.ToUpper(res.SignedByKeyIdHex()))
+		case errors.Is(err, pgperrors.ErrSignatureExpired), errors.Is(err, pgperrors.ErrKeyExpired):
+			return fmt.Errorf("%w: %w", ErrSignatureExpired, err)
+		default:
+			return fmt.Errorf("%w: %w", ErrSignatureBad, err)
+		}
+	}
+
+	if len(fingerprints) == 0 {
+		return nil
+	}
+
+	signers := []string{strings.ToUpper(hex.EncodeToString(res.SignedByFingerprint()))}
+	if key := res.SignedByKey(); key != nil {
+		signers = append(signers, strings.ToUpper(key.GetFingerprint()))
+	}
+
+	for _, fingerprint := range fingerprints {
+		if slices.Contains(signers, strings.ToUpper(fingerprint)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnexpectedSigner, signers[0])
+}
+
+// splitArmorBlocks splits the given content into separate armor blocks.
+func splitArmorBlocks(content string) []string {
+	result := make([]string, 0)
+
+	for {
+		start := strings.Index(content, armorBeginPrefix)
+		if start < 0 {
+			break
+		}
+
+		next := strings.Index(content[start+len(armorBeginPrefix):], armorBeginPrefix)
+		if next < 0 {
+			result = append(result, content[start:])
+
+			break
+		}
+
+		end := start + len(armorBeginPrefix) + next
+		result = append(result, content[start:end])
+		content = content[end:]
+	}
+
+	return result
+}
+
+// readHead returns the first bytes of the file at the given path.
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, headSize)
+
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return head[:n], nil
+}
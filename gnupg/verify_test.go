@@ -0,0 +1,297 @@
+package gnupg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ReadPublicKeys(t *testing.T) {
+	otherKey, err := crypto.PGP().KeyGeneration().AddUserId("Jane Doe", "jane.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	otherPublicKey, err := otherKey.GetArmoredPublicKey()
+	require.NoError(t, err)
+
+	// Keys that are not armored are passed base64 encoded and decoded before reading
+	otherBinaryKey, err := otherKey.GetPublicKey()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "public key",
+			content: testPublicKey,
+			want:    []string{testKeyFingerprint},
+		},
+		{
+			name:    "private key",
+			content: testPrivateKey,
+			want:    []string{testKeyFingerprint},
+		},
+		{
+			name:    "multiple keys",
+			content: testPublicKey + "\n" + otherPublicKey,
+			want:    []string{testKeyFingerprint, strings.ToUpper(otherKey.GetFingerprint())},
+		},
+		{
+			name:    "base64 decoded binary public key",
+			content: string(otherBinaryKey),
+			want:    []string{strings.ToUpper(otherKey.GetFingerprint())},
+		},
+		{
+			name:    "invalid binary key",
+			content: "invalid",
+			wantErr: ErrReadPublicKeyFailed,
+		},
+		{
+			name:    "empty content",
+			content: "",
+			wantErr: ErrNoPublicKeys,
+		},
+		{
+			name:    "invalid key",
+			content: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\ninvalid\n-----END PGP PUBLIC KEY BLOCK-----",
+			wantErr: ErrReadPublicKeyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{}
+
+			err := c.ReadPublicKeys(tt.content)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c.PublicKeys())
+		})
+	}
+}
+
+func TestClient_VerifyFile(t *testing.T) {
+	otherKey, err := crypto.PGP().KeyGeneration().AddUserId("Jane Doe", "jane.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	otherPublicKey, err := otherKey.GetArmoredPublicKey()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		armor        bool
+		detach       bool
+		clear        bool
+		unsigned     bool
+		tamper       bool
+		publicKey    string
+		fingerprints []string
+		verifyPath   func(path string) string
+		wantType     SignatureType
+		wantErr      error
+	}{
+		{
+			name:       "detached signature next to file",
+			detach:     true,
+			verifyPath: func(path string) string { return path },
+			wantType:   SignatureDetached,
+		},
+		{
+			name:         "armored detached signature",
+			armor:        true,
+			detach:       true,
+			fingerprints: []string{testKeyFingerprint},
+			verifyPath:   func(path string) string { return path + ".asc" },
+			wantType:     SignatureDetached,
+		},
+		{
+			name:       "cleartext signature",
+			clear:      true,
+			verifyPath: func(path string) string { return path + ".asc" },
+			wantType:   SignatureCleartext,
+		},
+		{
+			name:       "inline signature",
+			verifyPath: func(path string) string { return path + ".gpg" },
+			wantType:   SignatureInline,
+		},
+		{
+			name:       "armored inline signature",
+			armor:      true,
+			verifyPath: func(path string) string { return path + ".asc" },
+			wantType:   SignatureInline,
+		},
+		{
+			name:       "missing signature",
+			unsigned:   true,
+			verifyPath: func(path string) string { return path },
+			wantType:   SignatureDetached,
+			wantErr:    ErrSignatureMissing,
+		},
+		{
+			name:       "bad signature",
+			detach:     true,
+			tamper:     true,
+			verifyPath: func(path string) string { return path },
+			wantType:   SignatureDetached,
+			wantErr:    ErrSignatureBad,
+		},
+		{
+			name:         "unexpected fingerprint",
+			detach:       true,
+			fingerprints: []string{otherKey.GetFingerprint()},
+			verifyPath:   func(path string) string { return path },
+			wantType:     SignatureDetached,
+			wantErr:      ErrUnexpectedSigner,
+		},
+		{
+			name:       "unknown signer",
+			detach:     true,
+			publicKey:  otherPublicKey,
+			verifyPath: func(path string) string { return path },
+			wantType:   SignatureDetached,
+			wantErr:    ErrUnknownSigner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0o600))
+
+			if !tt.unsigned {
+				signer := &Client{
+					Backend: BackendNative,
+					Key:     Key{Content: testPrivateKey, Passphrase: testPassphrase},
+				}
+				require.NoError(t, signer.SignFile(t.Context(), tt.armor, tt.detach, tt.clear, path))
+			}
+
+			if tt.tamper {
+				require.NoError(t, os.WriteFile(path, []byte("hello tampered world\n"), 0o600))
+			}
+
+			publicKey := testPublicKey
+			if tt.publicKey != "" {
+				publicKey = tt.publicKey
+			}
+
+			c := &Client{}
+			require.NoError(t, c.ReadPublicKeys(publicKey))
+
+			got := c.VerifyFile(tt.verifyPath(path), tt.fingerprints)
+
+			assert.Equal(t, tt.wantType, got.Type)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, got.Err, tt.wantErr)
+
+				return
+			}
+
+			wantPath := path
+			if tt.wantType != SignatureDetached {
+				wantPath = tt.verifyPath(path)
+			}
+
+			assert.NoError(t, got.Err)
+			assert.Equal(t, wantPath, got.Path)
+			assert.Equal(t, testKeyFingerprint, got.Fingerprint)
+			assert.Equal(t, testKeyID, got.KeyID)
+			assert.False(t, got.CreationTime.IsZero())
+		})
+	}
+}
+
+func TestClient_VerifySignedFileTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		armor       bool
+		detach      bool
+		clear       bool
+		fingerprint string
+		corrupt     bool
+		wantErr     error
+	}{
+		{
+			name:   "detached signature",
+			detach: true,
+		},
+		{
+			name:   "armored detached signature",
+			armor:  true,
+			detach: true,
+		},
+		{
+			name:  "cleartext signature",
+			clear: true,
+		},
+		{
+			name: "inline signature",
+		},
+		{
+			name:    "corrupted signature",
+			detach:  true,
+			corrupt: true,
+			wantErr: ErrVerifySignedFile,
+		},
+		{
+			name:        "unexpected fingerprint",
+			detach:      true,
+			fingerprint: "0000000000000000000000000000000000000000",
+			wantErr:     ErrUnexpectedSigner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			require.NoError(t, os.WriteFile(path, []byte("hello world\n"), 0o600))
+
+			c := &Client{
+				Backend: BackendNative,
+				Key: Key{
+					Content:     testPrivateKey,
+					Passphrase:  testPassphrase,
+					Fingerprint: testKeyFingerprint,
+				},
+			}
+
+			// Signatures are verified at mirrored output paths as well
+			output := filepath.Join(t.TempDir(), "signatures", "file.sig")
+			require.NoError(t, os.MkdirAll(filepath.Dir(output), 0o700))
+			_, err := c.SignFileTo(t.Context(), tt.armor, tt.detach, tt.clear, path, output)
+			require.NoError(t, err)
+
+			if tt.corrupt {
+				require.NoError(t, os.WriteFile(output, []byte("corrupted"), 0o600))
+			}
+
+			if tt.fingerprint != "" {
+				c.Key.Fingerprint = tt.fingerprint
+			}
+
+			err = c.VerifySignedFileTo(tt.detach, tt.clear, path, output)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorContains(t, err, path)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var ErrNoReleaseFiles = errors.New("no release files found")
+
+const (
+	aptReleaseGlob   = "dists/*/Release"
+	aptReleaseSig    = "Release.gpg"
+	aptInReleaseFile = "InRelease"
+)
+
+// signApt signs all `dists/*/Release` files of the APT repository in the repository root.
+// For every release file an armored detached `Release.gpg` and a cleartext signed
+// `InRelease` file are written next to it.
+func (p *Plugin) signApt(ctx context.Context, gpgclient *gnupg.Client) error {
+	releases, _, err := expandGlobList([]string{filepath.Join(p.Settings.RepositoryRoot, aptReleaseGlob)})
+	if err != nil {
+		return fmt.Errorf("failed to find release files: %w", err)
+	}
+
+	releases = p.withoutExcludes(releases)
+	if len(releases) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoReleaseFiles, filepath.Join(p.Settings.RepositoryRoot, aptReleaseGlob))
+	}
+
+	requests := make([]gnupg.SignRequest, 0, len(releases)*2)
+
+	for _, path := range releases {
+		dir := filepath.Dir(path)
+
+		log.Info().Str("file", path).Msg("sign apt release file")
+
+		requests = append(requests,
+			gnupg.SignRequest{Path: path, Output: filepath.Join(dir, aptReleaseSig), Armor: true, DetachSign: true},
+			gnupg.SignRequest{Path: path, Output: filepath.Join(dir, aptInReleaseFile), Armor: true, ClearSign: true},
+		)
+	}
+
+	if err := p.signBatch(ctx, gpgclient, requests); err != nil {
+		return err
+	}
+
+	return p.exportPublicKey(gpgclient)
+}
+
+// exportPublicKey exports the public key into the repository root if configured.
+func (p *Plugin) exportPublicKey(gpgclient *gnupg.Client) error {
+	if p.Settings.ExportKey == "" {
+		return nil
+	}
+
+	path := filepath.Join(p.Settings.RepositoryRoot, p.Settings.ExportKey)
+
+	log.Info().Str("file", path).Msg("export public key")
+
+	return gpgclient.ExportPublicKey(path)
+}
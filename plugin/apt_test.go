@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+// newTestClient returns a client using the native backend with a freshly generated key.
+func newTestClient(t *testing.T) *gnupg.Client {
+	t.Helper()
+
+	key, err := crypto.PGP().KeyGeneration().AddUserId("John Doe", "john.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	armored, err := key.Armor()
+	require.NoError(t, err)
+
+	return &gnupg.Client{
+		Backend: gnupg.BackendNative,
+		Key: gnupg.Key{
+			Content:     armored,
+			Fingerprint: strings.ToUpper(key.GetFingerprint()),
+		},
+	}
+}
+
+func TestPlugin_SignApt(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    func(t *testing.T) *gnupg.Client
+		suites    []string
+		exportKey string
+		wantErr   error
+	}{
+		{
+			name:   "single suite",
+			client: newTestClient,
+			suites: []string{"stable"},
+		},
+		{
+			name:      "multiple suites with public key",
+			client:    newTestClient,
+			suites:    []string{"stable", "testing"},
+			exportKey: "Release.key",
+		},
+		{
+			name:   "multiple suites with gpg backend",
+			client: newTestGPGClient,
+			suites: []string{"stable", "testing"},
+		},
+		{
+			name:    "no release files",
+			client:  newTestClient,
+			wantErr: ErrNoReleaseFiles,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			for _, suite := range tt.suites {
+				dir := filepath.Join(root, "dists", suite)
+				require.NoError(t, os.MkdirAll(dir, 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "Release"), []byte("Suite: "+suite+"\n"), 0o600))
+			}
+
+			p := &Plugin{
+				Settings: &Settings{
+					RepositoryRoot:   root,
+					ExportKey:        tt.exportKey,
+					VerifySignatures: true,
+				},
+			}
+
+			err := p.signApt(t.Context(), tt.client(t))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for _, suite := range tt.suites {
+				dir := filepath.Join(root, "dists", suite)
+
+				sig, err := os.ReadFile(filepath.Join(dir, "Release.gpg"))
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(string(sig), "-----BEGIN PGP SIGNATURE-----"))
+
+				inRelease, err := os.ReadFile(filepath.Join(dir, "InRelease"))
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(string(inRelease), "-----BEGIN PGP SIGNED MESSAGE-----"))
+				assert.Contains(t, string(inRelease), "Suite: "+suite)
+
+				assert.NoFileExists(t, filepath.Join(dir, "Release.asc"))
+			}
+
+			if tt.exportKey != "" {
+				assert.FileExists(t, filepath.Join(root, tt.exportKey))
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var ErrNoArchFiles = errors.New("no arch packages or repository databases found")
+
+const archSignatureExt = ".sig"
+
+var (
+	// archPackageRegex matches package archives, e.g. `pkg-1.0-1-x86_64.pkg.tar.zst`.
+	archPackageRegex = regexp.MustCompile(`\.pkg\.tar(\.[a-z0-9]+)?$`)
+	// archDatabaseRegex matches repository databases, e.g. `repo.db.tar.zst`.
+	archDatabaseRegex = regexp.MustCompile(`\.(db|files)\.tar(\.[a-z0-9]+)?$`)
+)
+
+// signArch signs all Arch Linux packages and repository databases in the given files
+// with a binary detached `.sig` signature. For symlinks to a repository database, e.g.
+// `repo.db -> repo.db.tar.zst`, a `repo.db.sig -> repo.db.tar.zst.sig` symlink is
+// created as `repo-add` does.
+func (p *Plugin) signArch(ctx context.Context, gpgclient *gnupg.Client) error {
+	files := p.withoutExcludes(p.Settings.files)
+	signed := make(map[string]bool)
+	links := make([]string, 0)
+
+	for _, path := range files {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			links = append(links, path)
+
+			continue
+		}
+
+		if !isArchFile(path) {
+			p.Settings.report.addSkipped(path, SkipReasonUnsupported)
+
+			continue
+		}
+
+		if err := p.signArchFile(ctx, gpgclient, path, signed); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range links {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		resolved := target
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(path), target)
+		}
+
+		if !archDatabaseRegex.MatchString(resolved) {
+			p.Settings.report.addSkipped(path, SkipReasonUnsupported)
+
+			continue
+		}
+
+		if err := p.signArchFile(ctx, gpgclient, resolved, signed); err != nil {
+			return err
+		}
+
+		log.Info().Str("file", path).Msg("link arch repository database signature")
+
+		if err := replaceSymlink(target+archSignatureExt, path+archSignatureExt); err != nil {
+			return err
+		}
+	}
+
+	if len(signed) == 0 {
+		return ErrNoArchFiles
+	}
+
+	return nil
+}
+
+// signArchFile signs the file at the given path once and records it in signed.
+func (p *Plugin) signArchFile(ctx context.Context, gpgclient *gnupg.Client, path string, signed map[string]bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	if signed[abs] {
+		return nil
+	}
+
+	log.Info().Str("file", path).Msg("sign arch file")
+
+	if err := p.signFile(ctx, gpgclient, false, true, false, path); err != nil {
+		return err
+	}
+
+	signed[abs] = true
+
+	return nil
+}
+
+// isArchFile reports whether the file at the given path is a package or a repository database.
+func isArchFile(path string) bool {
+	return archPackageRegex.MatchString(path) || archDatabaseRegex.MatchString(path)
+}
+
+// replaceSymlink creates a symlink at the given path pointing to target. An existing
+// file at the path is replaced.
+func replaceSymlink(target, path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+
+	if err := os.Symlink(target, path); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsArchFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "demo-1.0-1-x86_64.pkg.tar.zst", want: true},
+		{path: "demo-1.0-1-x86_64.pkg.tar.xz", want: true},
+		{path: "repo.db.tar.zst", want: true},
+		{path: "repo.files.tar.gz", want: true},
+		{path: "repo.db.tar.zst.old", want: false},
+		{path: "repo.db.tar.zst.sig", want: false},
+		{path: "repo.db", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, isArchFile(tt.path))
+		})
+	}
+}
+
+func TestExpandGlobList(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repo.db.tar.zst"), []byte("db"), 0o600))
+	require.NoError(t, os.Symlink("repo.db.tar.zst", filepath.Join(dir, "repo.db")))
+	require.NoError(t, os.Symlink("missing", filepath.Join(dir, "dangling")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o755))
+
+	got, skipped, err := expandGlobList([]string{filepath.Join(dir, "*")})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "repo.db"),
+		filepath.Join(dir, "repo.db.tar.zst"),
+	}, got)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "dangling"),
+		filepath.Join(dir, "subdir"),
+	}, skipped)
+}
+
+func TestPlugin_SignArch(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"demo-1.0-1-x86_64.pkg.tar.zst", "repo.db.tar.zst", "repo.files.tar.zst", "README"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600))
+	}
+
+	require.NoError(t, os.Symlink("repo.db.tar.zst", filepath.Join(dir, "repo.db")))
+	require.NoError(t, os.Symlink("repo.files.tar.zst", filepath.Join(dir, "repo.files")))
+
+	files, _, err := expandGlobList([]string{filepath.Join(dir, "*")})
+	require.NoError(t, err)
+
+	p := &Plugin{
+		Settings: &Settings{
+			VerifySignatures: true,
+			files:            files,
+		},
+	}
+
+	gpgclient := newTestClient(t)
+
+	// The second run must replace the existing signature symlinks.
+	for range 2 {
+		require.NoError(t, p.signArch(t.Context(), gpgclient))
+	}
+
+	for _, name := range []string{"demo-1.0-1-x86_64.pkg.tar.zst", "repo.db.tar.zst", "repo.files.tar.zst"} {
+		assert.FileExists(t, filepath.Join(dir, name+".sig"))
+	}
+
+	for _, name := range []string{"repo.db", "repo.files"} {
+		target, err := os.Readlink(filepath.Join(dir, name+".sig"))
+		require.NoError(t, err)
+		assert.Equal(t, name+".tar.zst.sig", target)
+	}
+
+	assert.NoFileExists(t, filepath.Join(dir, "README.sig"))
+
+	p.Settings.files = []string{filepath.Join(dir, "README")}
+	assert.ErrorIs(t, p.signArch(t.Context(), gpgclient), ErrNoArchFiles)
+}
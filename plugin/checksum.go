@@ -0,0 +1,179 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var ErrInvalidChecksumAlgorithm = errors.New("invalid checksum algorithm")
+
+const checksumFilePerm = 0o644
+
+// newChecksumHash returns a new hash for the given algorithm name.
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return md5.New(), nil //nolint:gosec
+	case "sha1":
+		return sha1.New(), nil //nolint:gosec
+	case "sha224":
+		return sha256.New224(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidChecksumAlgorithm, algorithm)
+}
+
+// fileChecksum returns the hex encoded checksum of the file at the given path.
+func fileChecksum(path, algorithm string) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumFileName returns the name of the checksum file for the given algorithm.
+// The placeholder `{{algorithm}}` in the template is replaced by the upper case
+// algorithm name, e.g. `SHA256`.
+func checksumFileName(tmpl, algorithm string) string {
+	return strings.ReplaceAll(tmpl, "{{algorithm}}", strings.ToUpper(algorithm))
+}
+
+// writeChecksumFile hashes all given files and writes the result to a checksum file
+// in the format of the coreutils `sha256sum` tool. The file paths are written relative
+// to the directory of the checksum file.
+func writeChecksumFile(path, algorithm string, files []string) error {
+	var b strings.Builder
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		sum, err := fileChecksum(file, algorithm)
+		if err != nil {
+			return fmt.Errorf("failed to hash file %s: %w", file, err)
+		}
+
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&b, "%s  %s\n", sum, filepath.ToSlash(name))
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), checksumFilePerm); err != nil {
+		return fmt.Errorf("failed to write checksum file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// checksumFiles returns the paths of the checksum files for all configured algorithms.
+func (p *Plugin) checksumFiles() []string {
+	result := make([]string, 0, len(p.Settings.Checksums))
+
+	for _, algorithm := range p.Settings.Checksums {
+		result = append(result, checksumFileName(p.Settings.ChecksumFile, algorithm))
+	}
+
+	return result
+}
+
+// withoutChecksumFiles removes checksum files and their signatures from a previous
+// run from the given file list, as they must not be part of the checksum files. The
+// removed files are added to the report.
+func (p *Plugin) withoutChecksumFiles(files []string) []string {
+	generated := make(map[string]bool)
+
+	for _, path := range p.checksumFiles() {
+		names := []string{path}
+
+		if output, err := p.outputPath(path, ""); err == nil {
+			names = append(names, output)
+		}
+
+		for _, name := range names {
+			if abs, err := filepath.Abs(name); err == nil {
+				generated[abs] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil && generated[abs] {
+			p.Settings.report.addSkipped(file, SkipReasonChecksumFile)
+
+			continue
+		}
+
+		result = append(result, file)
+	}
+
+	return result
+}
+
+// signChecksums writes a checksum file for every configured algorithm and signs it.
+func (p *Plugin) signChecksums(ctx context.Context, gpgclient *gnupg.Client, files []string) error {
+	for _, algorithm := range p.Settings.Checksums {
+		path := checksumFileName(p.Settings.ChecksumFile, algorithm)
+
+		log.Info().Str("file", path).Str("algorithm", algorithm).
+			Msg("create checksum file")
+
+		if err := writeChecksumFile(path, algorithm, files); err != nil {
+			return err
+		}
+
+		if err := p.signOutput(ctx, gpgclient, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
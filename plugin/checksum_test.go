@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumFileName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tmpl      string
+		algorithm string
+		want      string
+	}{
+		{
+			name:      "default template",
+			tmpl:      "{{algorithm}}SUMS",
+			algorithm: "sha256",
+			want:      "SHA256SUMS",
+		},
+		{
+			name:      "custom path",
+			tmpl:      "dist/checksums.{{algorithm}}.txt",
+			algorithm: "sha512",
+			want:      "dist/checksums.SHA512.txt",
+		},
+		{
+			name:      "static name",
+			tmpl:      "CHECKSUMS",
+			algorithm: "md5",
+			want:      "CHECKSUMS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checksumFileName(tt.tmpl, tt.algorithm))
+		})
+	}
+}
+
+func TestWriteChecksumFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dist := filepath.Join(tmpDir, "dist")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dist, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dist, "a.txt"), []byte("hello\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dist, "sub", "b.txt"), []byte("world\n"), 0o600))
+
+	files := []string{filepath.Join(dist, "a.txt"), filepath.Join(dist, "sub", "b.txt")}
+
+	tests := []struct {
+		name      string
+		path      string
+		algorithm string
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "sha256",
+			path:      filepath.Join(dist, "SHA256SUMS"),
+			algorithm: "sha256",
+			want: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  a.txt\n" +
+				"e258d248fda94c63753607f7c4494ee0fcbe92f1a76bfdac795c9d84101eb317  sub/b.txt\n",
+		},
+		{
+			name:      "md5 in parent dir",
+			path:      filepath.Join(tmpDir, "MD5SUMS"),
+			algorithm: "md5",
+			want: "b1946ac92492d2347c6235b4d2611184  dist/a.txt\n" +
+				"591785b794601e212b260e25925636fd  dist/sub/b.txt\n",
+		},
+		{
+			name:      "invalid algorithm",
+			path:      filepath.Join(dist, "INVALIDSUMS"),
+			algorithm: "invalid",
+			wantErr:   ErrInvalidChecksumAlgorithm,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeChecksumFile(tt.path, tt.algorithm, files)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			got, err := os.ReadFile(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestPlugin_WithoutChecksumFiles(t *testing.T) {
+	p := &Plugin{
+		Settings: &Settings{
+			Checksums:    []string{"sha256", "sha512"},
+			ChecksumFile: "dist/{{algorithm}}SUMS",
+			DetachSign:   true,
+		},
+	}
+
+	files := []string{
+		"dist/app.tar.gz",
+		"dist/SHA256SUMS",
+		"dist/SHA256SUMS.sig",
+		"dist/SHA512SUMS",
+		"dist/app.zip",
+	}
+
+	assert.Equal(t, []string{"dist/app.tar.gz", "dist/app.zip"}, p.withoutChecksumFiles(files))
+}
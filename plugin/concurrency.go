@@ -0,0 +1,138 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var (
+	ErrSignFilesFailed    = errors.New("failed to sign files")
+	ErrInvalidConcurrency = errors.New("invalid concurrency")
+)
+
+const defaultConcurrency = 1
+
+// signJob is a file signed by the worker pool. The output of the gpg commands and the
+// log events are buffered until all previous files are done.
+type signJob struct {
+	path   string
+	output bytes.Buffer
+	logs   bytes.Buffer
+	err    error
+	done   chan struct{}
+}
+
+// signFiles signs the given files with the signing options from the settings on a pool
+// of workers. Every file is signed with its own copy of the client against the shared
+// homedir and gpg-agent. The output and the log events are written in the order of the
+// files, and a failed file does not abort the others. The errors of all failed files are
+// returned.
+func (p *Plugin) signFiles(ctx context.Context, gpgclient *gnupg.Client, files []string) error {
+	jobs := make([]*signJob, len(files))
+	queue := make(chan *signJob)
+
+	for i, path := range files {
+		jobs[i] = &signJob{path: path, done: make(chan struct{})}
+	}
+
+	var wg sync.WaitGroup
+
+	for range min(max(p.Settings.Concurrency, defaultConcurrency), len(files)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range queue {
+				logger := zerolog.New(&job.logs).Level(log.Logger.GetLevel())
+				jobCtx := logger.WithContext(ctx)
+
+				job.err = p.signOutput(jobCtx, gpgclient.WithOutput(&job.output), job.path)
+				close(job.done)
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			queue <- job
+		}
+
+		close(queue)
+	}()
+
+	var errs []error
+
+	for _, job := range jobs {
+		<-job.done
+
+		log.Info().Str("file", job.path).Msg("sign file")
+
+		if _, err := job.output.WriteTo(os.Stdout); err != nil {
+			log.Warn().Str("file", job.path).Msgf("failed to write gpg output: %s", err)
+		}
+
+		replayLogs(&job.logs)
+
+		if job.err != nil {
+			log.Error().Str("file", job.path).Err(job.err).Msg("failed to sign file")
+			p.Settings.report.addFailed(job.path, job.err)
+
+			errs = append(errs, fmt.Errorf("%s: %w", job.path, job.err))
+		}
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %d of %d files: %w", ErrSignFilesFailed, len(errs), len(files), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// replayLogs writes the JSON encoded log events buffered by a job to the global logger.
+// The time of the events is set when they are replayed.
+func replayLogs(r io.Reader) {
+	dec := json.NewDecoder(r)
+
+	for {
+		var fields map[string]any
+
+		if err := dec.Decode(&fields); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Warn().Msgf("failed to replay log event: %s", err)
+			}
+
+			return
+		}
+
+		level, err := zerolog.ParseLevel(fmt.Sprint(fields[zerolog.LevelFieldName]))
+		if err != nil {
+			level = zerolog.NoLevel
+		}
+
+		message, _ := fields[zerolog.MessageFieldName].(string)
+
+		delete(fields, zerolog.LevelFieldName)
+		delete(fields, zerolog.MessageFieldName)
+		delete(fields, zerolog.TimestampFieldName)
+
+		log.WithLevel(level).Fields(fields).Msg(message)
+	}
+}
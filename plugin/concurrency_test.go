@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+func TestPlugin_SignFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      func(t *testing.T) *gnupg.Client
+		concurrency int
+		files       int
+		missing     []string
+	}{
+		{
+			name:   "sequential",
+			client: newTestClient,
+			files:  3,
+		},
+		{
+			name:        "parallel",
+			client:      newTestClient,
+			concurrency: 4,
+			files:       20,
+		},
+		{
+			name:        "parallel with failed files",
+			client:      newTestClient,
+			concurrency: 4,
+			files:       10,
+			missing:     []string{"missing-1.txt", "missing-2.txt"},
+		},
+		{
+			name:        "parallel gpg",
+			client:      newTestGPGClient,
+			concurrency: 4,
+			files:       8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			files := make([]string, 0, tt.files+len(tt.missing))
+
+			for i := range tt.files {
+				path := filepath.Join(tmpDir, fmt.Sprintf("file-%d.txt", i))
+				require.NoError(t, os.WriteFile(path, []byte(path), 0o600))
+
+				files = append(files, path)
+			}
+
+			for _, name := range tt.missing {
+				files = append(files, filepath.Join(tmpDir, name))
+			}
+
+			gpgclient := tt.client(t)
+
+			p := &Plugin{
+				Settings: &Settings{
+					DetachSign:       true,
+					Concurrency:      tt.concurrency,
+					VerifySignatures: true,
+					signingKeys:      []gnupg.Key{gpgclient.Key},
+					report:           newReport(ModeSign, gpgclient.Backend),
+				},
+			}
+
+			err := p.signFiles(t.Context(), gpgclient, files)
+			if len(tt.missing) > 0 {
+				assert.ErrorIs(t, err, ErrSignFilesFailed)
+
+				for _, name := range tt.missing {
+					assert.ErrorContains(t, err, name)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for _, path := range files[:tt.files] {
+				assert.FileExists(t, path+".sig")
+			}
+
+			assert.Len(t, p.Settings.report.Signatures, tt.files)
+		})
+	}
+}
+
+func TestPlugin_ValidateConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantErr     error
+	}{
+		{
+			name:        "default",
+			concurrency: defaultConcurrency,
+		},
+		{
+			name:        "zero",
+			concurrency: 0,
+		},
+		{
+			name:        "negative",
+			concurrency: -1,
+			wantErr:     ErrInvalidConcurrency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				Settings: &Settings{
+					Mode:        ModeSign,
+					Backend:     gnupg.BackendNative,
+					MultiSign:   MultiSignSeparate,
+					Concurrency: tt.concurrency,
+					keys:        []KeyEntry{{Key: "key"}},
+				},
+			}
+
+			err := p.Validate()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPlugin_SignFilesLogOrder(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := log.Logger
+	log.Logger = zerolog.New(&logs)
+
+	t.Cleanup(func() { log.Logger = logger })
+
+	tmpDir := t.TempDir()
+	files := make([]string, 0)
+
+	for i := range 8 {
+		path := filepath.Join(tmpDir, fmt.Sprintf("file-%d.txt", i))
+		require.NoError(t, os.WriteFile(path, []byte(path), 0o600))
+
+		files = append(files, path)
+	}
+
+	gpgclient := newTestClient(t)
+
+	p := &Plugin{
+		Settings: &Settings{
+			DetachSign:  true,
+			Concurrency: 4,
+			MultiSign:   MultiSignCombined,
+			signingKeys: []gnupg.Key{gpgclient.Key, newTestClient(t).Key},
+		},
+	}
+
+	require.NoError(t, p.signFiles(t.Context(), gpgclient, files))
+
+	// Every file is followed by the log events of its job
+	var got []string
+
+	dec := json.NewDecoder(&logs)
+
+	for dec.More() {
+		var event struct {
+			File    string `json:"file"`
+			Message string `json:"message"`
+		}
+
+		require.NoError(t, dec.Decode(&event))
+
+		got = append(got, event.Message+" "+filepath.Base(event.File))
+	}
+
+	want := make([]string, 0)
+	for _, path := range files {
+		want = append(want, "sign file "+filepath.Base(path), "combine signatures "+filepath.Base(path)+".sig")
+	}
+
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+// failureReason returns a short reason and an actionable message for known failures
+// reported by gpg or the key checks. Empty strings are returned for other errors.
+func failureReason(err error) (string, string) {
+	reasons := []struct {
+		err    error
+		reason string
+		hint   string
+	}{
+		{
+			err:    gnupg.ErrBadPassphrase,
+			reason: "bad_passphrase",
+			hint:   "the passphrase does not unlock the signing key: check `passphrase` or `passphrase_file`",
+		},
+		{
+			err:    gnupg.ErrMissingPassphrase,
+			reason: "missing_passphrase",
+			hint:   "the signing key is protected by a passphrase: set `passphrase` or `passphrase_file`",
+		},
+		{
+			err:    gnupg.ErrNoSecretKey,
+			reason: "no_secret_key",
+			hint:   "the secret key is not available: check that `key` contains the private key of `fingerprint`",
+		},
+		{
+			err:    gnupg.ErrKeyExpired,
+			reason: "key_expired",
+			hint:   "the signing key is expired: extend the expiration date of the key or use another key",
+		},
+		{
+			err:    gnupg.ErrKeyRevoked,
+			reason: "key_revoked",
+			hint:   "the signing key is revoked: use another key",
+		},
+		{
+			err:    gnupg.ErrKeyCannotSign,
+			reason: "key_cannot_sign",
+			hint:   "the key can not be used for signing: set `fingerprint` to a subkey with the signing capability",
+		},
+		{
+			err:    gnupg.ErrInvalidSigner,
+			reason: "invalid_signer",
+			hint:   "gpg rejected the signing key: check `fingerprint` and the key",
+		},
+		{
+			err:    gnupg.ErrCommandTimeout,
+			reason: "timeout",
+			hint:   "a gpg or git command did not finish in time: check for a hanging gpg-agent or increase `command_timeout`",
+		},
+		{
+			err:    gnupg.ErrCommandCanceled,
+			reason: "canceled",
+			hint:   "a gpg or git command was canceled: the step was stopped before signing finished",
+		},
+	}
+
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return r.reason, r.hint
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "bad passphrase",
+			err:  fmt.Errorf("failed to sign file: %w", gnupg.ErrBadPassphrase),
+			want: "bad_passphrase",
+		},
+		{
+			name: "invalid signer with expired key",
+			err:  fmt.Errorf("%w: %w", gnupg.ErrInvalidSigner, gnupg.ErrKeyExpired),
+			want: "key_expired",
+		},
+		{
+			name: "invalid signer",
+			err:  gnupg.ErrInvalidSigner,
+			want: "invalid_signer",
+		},
+		{
+			name: "timeout",
+			err:  fmt.Errorf("failed to import gpg key: %w", gnupg.ErrCommandTimeout),
+			want: "timeout",
+		},
+		{
+			name: "canceled",
+			err:  fmt.Errorf("failed to run git tag: %w", gnupg.ErrCommandCanceled),
+			want: "canceled",
+		},
+		{
+			name: "native unlock with bad passphrase",
+			err:  fmt.Errorf("%w: %w", gnupg.ErrUnlockKeyFailed, gnupg.ErrBadPassphrase),
+			want: "bad_passphrase",
+		},
+		{
+			name: "unknown error",
+			err:  os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, hint := failureReason(tt.err)
+			assert.Equal(t, tt.want, reason)
+			assert.Equal(t, tt.want == "", hint == "")
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+	"golang.org/x/sys/execabs"
+)
+
+var (
+	ErrInvalidGitConfig = errors.New("invalid git config scope")
+	ErrGitBackend       = errors.New("git signing requires the gpg backend")
+	ErrGitTagRequired   = errors.New("git tag is required")
+
+	ErrGitPresetPassphrase = errors.New("git config for a key with passphrase requires preset passphrase")
+)
+
+const (
+	GitConfigGlobal = "global"
+	GitConfigLocal  = "local"
+
+	gitBin = "git"
+
+	// gitWaitDelay is the time to wait for the output of a killed git command, e.g. if
+	// the gpg program started by git keeps the error output open.
+	gitWaitDelay = 5 * time.Second
+)
+
+// setupGit persists the gpg home directory and writes a git config for the configured
+// scope that enables commit and tag signing with the key in use.
+func (p *Plugin) setupGit(ctx context.Context, gpgclient *gnupg.Client) error {
+	program, err := gpgclient.PersistHomedir()
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("scope", p.Settings.GitConfig).Msg("write git config")
+
+	for _, entry := range gitSigningConfig(gpgclient, program) {
+		if _, err := p.runGit(ctx, nil, "config", "--"+p.Settings.GitConfig, entry[0], entry[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signGit creates a signed annotated tag in the repository of the working directory.
+// An existing tag is re-signed for the commit it points to, otherwise the tag is created
+// for the configured commit. If configured, a signed archive of the tag is created.
+func (p *Plugin) signGit(ctx context.Context, gpgclient *gnupg.Client) error {
+	program, err := gpgclient.WriteGPGProgram()
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, 0)
+	for _, entry := range gitSigningConfig(gpgclient, program) {
+		args = append(args, "-c", entry[0]+"="+entry[1])
+	}
+
+	tag := p.Settings.GitTag
+	commit := p.Settings.GitCommit
+
+	existing, err := p.runGit(ctx, gpgclient.Env, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag+"^{commit}")
+	if err == nil {
+		log.Info().Str("tag", tag).Str("commit", existing).Msg("re-sign existing git tag")
+
+		commit = existing
+	}
+
+	message := p.Settings.GitTagMessage
+	if message == "" {
+		message = tag
+	}
+
+	log.Info().Str("tag", tag).Str("commit", commit).Msg("create signed git tag")
+
+	args = append(args, "tag", "--sign", "--force", "--message", message, tag, commit)
+	if _, err := p.runGit(ctx, gpgclient.Env, args...); err != nil {
+		return err
+	}
+
+	if p.Settings.GitArchive == "" {
+		return nil
+	}
+
+	return p.signGitArchive(ctx, gpgclient, tag)
+}
+
+// signGitArchive creates an archive of the given tag and signs it with a detached signature.
+// The archive format is derived from the file extension by git.
+func (p *Plugin) signGitArchive(ctx context.Context, gpgclient *gnupg.Client, tag string) error {
+	path := p.Settings.GitArchive
+	prefix := filepath.Base(path)
+
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		prefix = strings.TrimSuffix(prefix, ext)
+	}
+
+	log.Info().Str("file", path).Str("tag", tag).Msg("create git archive")
+
+	if _, err := p.runGit(ctx, nil, "archive", "--prefix", prefix+"/", "--output", path, tag); err != nil {
+		return err
+	}
+
+	return p.signFile(ctx, gpgclient, p.Settings.Armor, true, false, path)
+}
+
+// gitSigningConfig returns the git config entries to sign commits and tags with the key
+// of the client using the given gpg program.
+func gitSigningConfig(gpgclient *gnupg.Client, program string) [][]string {
+	name, email := gnupg.ParseIdentity(gpgclient.Key.Identity)
+
+	config := [][]string{
+		{"user.signingkey", gpgclient.Key.Fingerprint + "!"},
+		{"gpg.program", program},
+		{"commit.gpgsign", "true"},
+		{"tag.gpgsign", "true"},
+	}
+
+	if name != "" {
+		config = append(config, []string{"user.name", name})
+	}
+
+	if email != "" {
+		config = append(config, []string{"user.email", email})
+	}
+
+	return config
+}
+
+// runGit runs git with the given arguments and additional environment variables and
+// returns the trimmed output. Like the gpg commands of the client, git is killed if the
+// context is canceled or takes longer than the command timeout, and the recorded error
+// output describes the failure.
+func (p *Plugin) runGit(ctx context.Context, env []string, args ...string) (string, error) {
+	absBin, err := execabs.LookPath(gitBin)
+	if err != nil {
+		return "", fmt.Errorf("could not find executable %q: %w", gitBin, err)
+	}
+
+	timeout := p.Settings.CommandTimeout
+	cmdCtx, cancel := ctx, context.CancelFunc(func() {})
+
+	if timeout > 0 {
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := plugin_exec.CommandContext(cmdCtx, absBin, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.WaitDelay = gitWaitDelay
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	if err := cmd.Run(); err != nil {
+		var desc string
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			desc = ": stderr: " + strings.ReplaceAll(msg, "\n", "; ")
+		}
+
+		if cmdCtx.Err() == nil {
+			return "", fmt.Errorf("failed to run git %s: %w%s", args[0], err, desc)
+		}
+
+		desc = strings.Join(cmd.Args, " ") + desc
+
+		if cause := ctx.Err(); cause != nil {
+			return "", fmt.Errorf("%w: %s: %w", gnupg.ErrCommandCanceled, desc, cause)
+		}
+
+		return "", fmt.Errorf("%w after %s: %s", gnupg.ErrCommandTimeout, timeout, desc)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,268 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+func TestPlugin_SetupGit(t *testing.T) {
+	for _, bin := range []string{gitBin, "gpg"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not installed", bin)
+		}
+	}
+
+	gpgclient := newTestGPGClient(t)
+	gpgclient.Key.Identity = "John Doe (CI) <john.doe@example.com>"
+
+	t.Chdir(t.TempDir())
+	require.NoError(t, exec.Command(gitBin, "init", "--quiet").Run())
+
+	p := &Plugin{
+		Settings: &Settings{
+			GitConfig: GitConfigLocal,
+		},
+	}
+
+	require.NoError(t, p.setupGit(t.Context(), gpgclient))
+
+	want := map[string]string{
+		"user.signingkey": gpgclient.Key.Fingerprint + "!",
+		"gpg.program":     filepath.Join(gpgclient.Homedir, "gpg-wrapper"),
+		"commit.gpgsign":  "true",
+		"tag.gpgsign":     "true",
+		"user.name":       "John Doe",
+		"user.email":      "john.doe@example.com",
+	}
+
+	for key, value := range want {
+		out, err := exec.Command(gitBin, "config", "--local", key).Output()
+		require.NoError(t, err)
+		assert.Equal(t, value, strings.TrimSpace(string(out)), key)
+	}
+
+	assert.NoError(t, gpgclient.Cleanup(t.Context()))
+	assert.DirExists(t, gpgclient.Homedir)
+}
+
+func TestPlugin_ValidateGitConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		preset     bool
+		wantErr    error
+	}{
+		{
+			name: "without passphrase",
+		},
+		{
+			name:       "with preset passphrase",
+			passphrase: "secret",
+			preset:     true,
+		},
+		{
+			name:       "with passphrase not preset",
+			passphrase: "secret",
+			wantErr:    ErrGitPresetPassphrase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				Settings: &Settings{
+					Mode:             ModeSign,
+					Backend:          gnupg.BackendGPG,
+					GitConfig:        GitConfigLocal,
+					MultiSign:        MultiSignSeparate,
+					PresetPassphrase: tt.preset,
+					keys:             []KeyEntry{{Key: "key", Passphrase: tt.passphrase}},
+				},
+			}
+
+			err := p.Validate()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPlugin_SignGit(t *testing.T) {
+	for _, bin := range []string{gitBin, "gpg"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not installed", bin)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{
+			name: "new tag",
+		},
+		{
+			name:     "re-sign lightweight tag",
+			existing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			git := func(args ...string) string {
+				t.Helper()
+
+				cmd := exec.Command(gitBin, args...)
+				cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+					"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+
+				out, err := cmd.Output()
+				require.NoError(t, err, args)
+
+				return strings.TrimSpace(string(out))
+			}
+
+			git("init", "--quiet")
+			require.NoError(t, os.WriteFile("README", []byte("demo"), 0o600))
+			git("add", "README")
+			git("commit", "--quiet", "--no-gpg-sign", "--message", "first")
+			first := git("rev-parse", "HEAD")
+			git("commit", "--quiet", "--no-gpg-sign", "--allow-empty", "--message", "second")
+
+			if tt.existing {
+				git("tag", "v1.0.0", first)
+			}
+
+			gpgclient := newTestGPGClient(t)
+
+			p := &Plugin{
+				Settings: &Settings{
+					GitTag:     "v1.0.0",
+					GitCommit:  "HEAD",
+					GitArchive: filepath.Join(t.TempDir(), "demo-1.0.0.tar.gz"),
+					Armor:      true,
+				},
+			}
+
+			require.NoError(t, p.signGit(t.Context(), gpgclient))
+
+			assert.Equal(t, "tag", git("cat-file", "-t", "v1.0.0"))
+			assert.Contains(t, git("cat-file", "-p", "v1.0.0"), "-----BEGIN PGP SIGNATURE-----")
+
+			want := git("rev-parse", "HEAD")
+			if tt.existing {
+				want = first
+			}
+
+			assert.Equal(t, want, git("rev-parse", "v1.0.0^{commit}"))
+			assert.FileExists(t, p.Settings.GitArchive)
+			assert.FileExists(t, p.Settings.GitArchive+".asc")
+		})
+	}
+}
+
+func TestPlugin_RunGit(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		cancel  bool
+		want    string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:   "output",
+			script: "echo ' abc123 '",
+			want:   "abc123",
+		},
+		{
+			name:    "failure with error output",
+			script:  "echo 'fatal: not a git repository' >&2; exit 128",
+			wantMsg: "failed to run git log: exit status 128: stderr: fatal: not a git repository",
+		},
+		{
+			name:    "timeout",
+			script:  "echo 'waiting for gpg' >&2; exec sleep 60",
+			wantErr: gnupg.ErrCommandTimeout,
+			wantMsg: "stderr: waiting for gpg",
+		},
+		{
+			name:    "canceled",
+			script:  "exec sleep 60",
+			cancel:  true,
+			wantErr: gnupg.ErrCommandCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(bin, gitBin), []byte("#!/bin/sh\n"+tt.script+"\n"), 0o700))
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			ctx, cancel := context.WithCancel(t.Context())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			p := &Plugin{Settings: &Settings{CommandTimeout: 200 * time.Millisecond}}
+
+			out, err := p.runGit(ctx, nil, "log", "-1")
+			if tt.wantErr != nil || tt.wantMsg != "" {
+				require.Error(t, err)
+
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+
+				assert.Contains(t, err.Error(), tt.wantMsg)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+// newTestGPGClient returns a client using the gpg backend with a freshly generated key
+// imported into a temporary homedir.
+func newTestGPGClient(t *testing.T) *gnupg.Client {
+	t.Helper()
+
+	key := newTestClient(t).Key
+
+	// Keep the default homedir of the client out of the real home directory
+	t.Setenv("HOME", t.TempDir())
+
+	gpgclient, err := gnupg.New(key.Content, "")
+	require.NoError(t, err)
+	require.NoError(t, gpgclient.ReadPrivateKey())
+	require.NoError(t, gpgclient.ImportKey(t.Context()))
+
+	t.Cleanup(func() {
+		cmd := exec.Command("gpgconf", "--kill", "gpg-agent")
+		cmd.Env = append(os.Environ(), gpgclient.Env...)
+		_ = cmd.Run()
+	})
+
+	return gpgclient
+}
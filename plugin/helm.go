@@ -0,0 +1,161 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var (
+	ErrNoCharts         = errors.New("no helm charts found")
+	ErrChartYamlMissing = errors.New("chart archive contains no Chart.yaml")
+)
+
+const (
+	helmChartExt  = ".tgz"
+	helmProvExt   = ".prov"
+	helmChartYaml = "Chart.yaml"
+	provFilePerm  = 0o600
+)
+
+// signHelm creates a clearsigned provenance file `<chart>.tgz.prov` for every
+// packaged Helm chart in the given files.
+func (p *Plugin) signHelm(ctx context.Context, gpgclient *gnupg.Client) error {
+	charts := make([]string, 0)
+
+	for _, path := range p.withoutExcludes(p.Settings.files) {
+		if !strings.HasSuffix(path, helmChartExt) {
+			p.Settings.report.addSkipped(path, SkipReasonUnsupported)
+
+			continue
+		}
+
+		charts = append(charts, path)
+	}
+
+	if len(charts) == 0 {
+		return ErrNoCharts
+	}
+
+	tmpdir, err := os.MkdirTemp("", "wp-gpgsign-helm-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	for _, path := range charts {
+		log.Info().Str("file", path).Msg("create helm provenance file")
+
+		block, err := helmMessageBlock(path)
+		if err != nil {
+			return err
+		}
+
+		msgPath := filepath.Join(tmpdir, filepath.Base(path)+helmProvExt)
+
+		if err := os.WriteFile(msgPath, block, provFilePerm); err != nil {
+			return fmt.Errorf("failed to write provenance message: %w", err)
+		}
+
+		if err := p.signFileTo(ctx, gpgclient, true, false, true, msgPath, path+helmProvExt); err != nil {
+			return err
+		}
+
+		// The chart is the signed file, the message block is only an intermediate file
+		err = p.Settings.report.updateSignatures([]string{path + helmProvExt}, func(entry *ReportSignature) error {
+			return entry.setFile(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.exportPublicKey(gpgclient)
+}
+
+// helmMessageBlock builds the provenance message block of the chart archive at the
+// given path in the format Helm expects. It consists of the chart metadata and the
+// sha256 checksum of the archive, separated by the YAML document end marker.
+func helmMessageBlock(path string) ([]byte, error) {
+	metadata, err := readChartYaml(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := fileChecksum(path, "sha256")
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	// The YAML document start marker is not allowed in a clearsigned message and
+	// the end marker is used as separator.
+	for _, line := range strings.Split(strings.TrimRight(string(metadata), "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" || trimmed == "..." {
+			continue
+		}
+
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n...\n")
+	fmt.Fprintf(&b, "files:\n  %s: sha256:%s\n", filepath.Base(path), sum)
+
+	return b.Bytes(), nil
+}
+
+// readChartYaml returns the content of the top level Chart.yaml in the chart archive.
+func readChartYaml(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart archive %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chart archive %s: %w", path, err)
+		}
+
+		// Charts are packaged into a single top level directory. Chart.yaml files
+		// of subcharts are nested deeper and are skipped.
+		parts := strings.Split(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if len(parts) != 2 || parts[1] != helmChartYaml {
+			continue
+		}
+
+		return io.ReadAll(tr)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrChartYamlMissing, path)
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChartYaml = `---
+apiVersion: v2
+name: demo
+version: 0.1.0
+`
+
+// writeTestChart writes a packaged chart with the given files to the given path.
+func writeTestChart(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+}
+
+func TestHelmMessageBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "chart with subchart",
+			files: map[string]string{
+				"demo/charts/sub/Chart.yaml": "name: sub\n",
+				"demo/Chart.yaml":            testChartYaml,
+			},
+			want: "apiVersion: v2\nname: demo\nversion: 0.1.0\n\n...\nfiles:\n  demo-0.1.0.tgz: sha256:",
+		},
+		{
+			name:    "missing Chart.yaml",
+			files:   map[string]string{"demo/values.yaml": "{}\n"},
+			wantErr: ErrChartYamlMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "demo-0.1.0.tgz")
+			writeTestChart(t, path, tt.files)
+
+			got, err := helmMessageBlock(path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			sum, err := fileChecksum(path, "sha256")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want+sum+"\n", string(got))
+		})
+	}
+}
+
+func TestPlugin_SignHelm(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "demo-0.1.0.tgz")
+	writeTestChart(t, chart, map[string]string{"demo/Chart.yaml": testChartYaml})
+
+	p := &Plugin{
+		Settings: &Settings{
+			RepositoryRoot:   dir,
+			ExportKey:        "pubkey.asc",
+			VerifySignatures: true,
+			files:            []string{chart, filepath.Join(dir, "README.md")},
+		},
+	}
+
+	require.NoError(t, p.signHelm(t.Context(), newTestClient(t)))
+
+	prov, err := os.ReadFile(chart + ".prov")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(prov), "-----BEGIN PGP SIGNED MESSAGE-----"))
+	assert.Contains(t, string(prov), "files:\n  demo-0.1.0.tgz: sha256:")
+	assert.FileExists(t, filepath.Join(dir, "pubkey.asc"))
+	assert.NoFileExists(t, filepath.Join(dir, "README.md.prov"))
+
+	p.Settings.files = nil
+	assert.ErrorIs(t, p.signHelm(t.Context(), newTestClient(t)), ErrNoCharts)
+}
@@ -8,8 +8,12 @@ package plugin
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/thegeeklab/wp-gpgsign/gnupg"
@@ -17,6 +21,34 @@ import (
 	plugin_slice "github.com/thegeeklab/wp-plugin-go/v6/slice"
 )
 
+var (
+	ErrInvalidBackend    = errors.New("invalid signing backend")
+	ErrInvalidMode       = errors.New("invalid plugin mode")
+	ErrKeyRequired       = errors.New("private key is required")
+	ErrPublicKeyRequired = errors.New("public key is required")
+	ErrInsecureFilePerm  = errors.New("secret file must not be readable by group or others")
+)
+
+const (
+	ModeSign      = "sign"
+	ModeVerify    = "verify"
+	ModeApt       = "apt"
+	ModeRpm       = "rpm"
+	ModeHelm      = "helm"
+	ModeTerraform = "terraform"
+	ModeMaven     = "maven"
+	ModeArch      = "arch"
+	ModeGit       = "git"
+
+	// secretFilePermMask matches the read permissions of group and others.
+	secretFilePermMask = 0o044
+
+	// defaultKeyExpiryWarn is the default window before the key expiry to warn about it.
+	defaultKeyExpiryWarn = 30 * 24 * time.Hour
+	// defaultPassphraseCacheTTL is the default TTL of passphrases cached by the gpg-agent.
+	defaultPassphraseCacheTTL = 2 * time.Hour
+)
+
 func (p *Plugin) run(ctx context.Context) error {
 	if err := p.FlagsFromContext(); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
@@ -26,7 +58,11 @@ func (p *Plugin) run(ctx context.Context) error {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	if err := p.Execute(); err != nil {
+	if err := p.Execute(ctx); err != nil {
+		if reason, hint := failureReason(err); reason != "" {
+			log.Error().Str("reason", reason).Msg(hint)
+		}
+
 		return fmt.Errorf("execution failed: %w", err)
 	}
 
@@ -37,56 +73,147 @@ func (p *Plugin) run(ctx context.Context) error {
 func (p *Plugin) FlagsFromContext() error {
 	var err error
 
+	if p.Settings.ReportFile != "" || p.Settings.ReportDotenv != "" {
+		p.Settings.report = newReport(p.Settings.Mode, p.Settings.Backend)
+	}
+
 	rawFiles := plugin_slice.Unique(p.App.StringSlice("files"))
 
-	p.Settings.files, err = expandGlobList(rawFiles)
+	var skipped []string
+
+	p.Settings.files, skipped, err = expandGlobList(rawFiles)
 	if err != nil {
 		return fmt.Errorf("failed to parse files: %w", err)
 	}
 
-	p.Settings.setupOnly = (len(p.Settings.files) < 1)
+	for _, path := range skipped {
+		p.Settings.report.addSkipped(path, SkipReasonNotRegular)
+	}
+
+	p.Settings.setupOnly = (p.Settings.Mode == ModeSign && len(p.Settings.files) < 1)
 
 	rawExcludes := plugin_slice.Unique(p.App.StringSlice("excludes"))
 
-	p.Settings.excludes, err = expandGlobList(rawExcludes)
+	p.Settings.excludes, _, err = expandGlobList(rawExcludes)
 	if err != nil {
 		return fmt.Errorf("failed to parse excludes: %w", err)
 	}
 
-	p.Settings.Key = p.App.String("key")
+	rawKey := p.App.String("key")
 
-	if gnupg.IsArmored(p.Settings.Key) {
-		return nil
+	if p.Settings.KeyFile != "" {
+		rawKey, err = readSecretFile(p.Settings.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read key file: %w", err)
+		}
 	}
 
-	byteKey, err := base64.StdEncoding.DecodeString(p.Settings.Key)
+	p.Settings.Key, err = decodeKey(rawKey)
 	if err != nil {
-		return fmt.Errorf("failed to parse key: not armored but failed to base64 decode: %w", err)
+		return fmt.Errorf("failed to parse key: %w", err)
 	}
 
-	p.Settings.Key = string(byteKey)
+	if p.Settings.PassphraseFile != "" {
+		p.Settings.Passphrase, err = readSecretFile(p.Settings.PassphraseFile)
+		if err != nil {
+			return fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+	}
+
+	p.Settings.PublicKey, err = decodeKey(p.App.String("public-key"))
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	p.Settings.keys, err = p.keyEntries(p.App.String("keys"))
+	if err != nil {
+		return fmt.Errorf("failed to parse keys: %w", err)
+	}
 
 	return nil
 }
 
 // Validate handles the settings validation of the plugin.
 func (p *Plugin) Validate() error {
-	return nil
+	switch p.Settings.Mode {
+	case ModeSign, ModeApt, ModeRpm, ModeHelm, ModeTerraform, ModeMaven, ModeArch:
+		if len(p.Settings.keys) == 0 {
+			return ErrKeyRequired
+		}
+	case ModeGit:
+		if len(p.Settings.keys) == 0 {
+			return ErrKeyRequired
+		}
+
+		if p.Settings.GitTag == "" {
+			return ErrGitTagRequired
+		}
+
+		if p.Settings.Backend != gnupg.BackendGPG {
+			return ErrGitBackend
+		}
+	case ModeVerify:
+		if p.Settings.PublicKey == "" {
+			return ErrPublicKeyRequired
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidMode, p.Settings.Mode)
+	}
+
+	if !slices.Contains([]string{gnupg.BackendGPG, gnupg.BackendNative}, p.Settings.Backend) {
+		return fmt.Errorf("%w: %s", ErrInvalidBackend, p.Settings.Backend)
+	}
+
+	if p.Settings.Concurrency < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidConcurrency, p.Settings.Concurrency)
+	}
+
+	if p.Settings.GitConfig != "" {
+		if !slices.Contains([]string{GitConfigGlobal, GitConfigLocal}, p.Settings.GitConfig) {
+			return fmt.Errorf("%w: %s", ErrInvalidGitConfig, p.Settings.GitConfig)
+		}
+
+		if p.Settings.Backend != gnupg.BackendGPG {
+			return ErrGitBackend
+		}
+
+		if !p.Settings.PresetPassphrase && slices.ContainsFunc(p.Settings.keys, func(entry KeyEntry) bool {
+			return entry.Passphrase != ""
+		}) {
+			return ErrGitPresetPassphrase
+		}
+	}
+
+	for _, algorithm := range p.Settings.Checksums {
+		if _, err := newChecksumHash(algorithm); err != nil {
+			return err
+		}
+	}
+
+	if err := p.validateSignatureTime(); err != nil {
+		return err
+	}
+
+	return p.validateKeys()
 }
 
 // Execute provides the implementation of the plugin.
-func (p *Plugin) Execute() error {
+func (p *Plugin) Execute(ctx context.Context) error {
 	var err error
 
-	gpgclient, err := gnupg.New(p.Settings.Key, p.Settings.Passphrase)
+	gpgclient, err := gnupg.New("", "")
 	if err != nil {
 		return err
 	}
 
 	defer func() {
-		_ = gpgclient.Cleanup()
+		_ = gpgclient.Cleanup(ctx)
 	}()
 
+	if p.Settings.Mode == ModeVerify {
+		return p.verify(gpgclient)
+	}
+
 	if p.Settings.setupOnly {
 		log.Info().Msg("no files found: running in setup-only mode")
 	}
@@ -99,99 +226,417 @@ func (p *Plugin) Execute() error {
 		}
 	}
 
-	// Get gpg info
-	version, err := gpgclient.GetVersion()
+	gpgclient.Backend = p.Settings.Backend
+	gpgclient.Timeout = p.Settings.CommandTimeout
+
+	log.Info().Str("backend", gpgclient.Backend).
+		Msg("use signing backend")
+
+	signatureTime, err := p.signatureTime(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = gpgclient.GetDirs()
+	if !signatureTime.IsZero() {
+		log.Info().Time("time", signatureTime).Msg("pin signature time")
+
+		gpgclient.SignatureTime = signatureTime
+	}
+
+	// Get gpg info
+	if gpgclient.Backend == gnupg.BackendGPG {
+		version, err := gpgclient.GetVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = gpgclient.GetDirs(ctx)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Msgf("read private key and environment metadata")
+
+		fmt.Print(
+			"GnuPG info\n",
+			fmt.Sprintf("Version    : %s (libgcrypt %s)\n", version.Gnupg, version.Libgcrypt),
+			fmt.Sprintf("Libdir     : %s\n", gpgclient.Dirs.Lib),
+			fmt.Sprintf("Libexecdir : %s\n", gpgclient.Dirs.Libexec),
+			fmt.Sprintf("Datadir    : %s\n", gpgclient.Dirs.Data),
+			fmt.Sprintf("Homedir    : %s\n", gpgclient.Dirs.Home),
+			"\n",
+		)
+
+		if p.Settings.PresetPassphrase {
+			log.Info().Dur("ttl", p.Settings.PassphraseCacheTTL).
+				Msg("configure gpg-agent to preset passphrases")
+
+			if err := gpgclient.ConfigureAgent(ctx, p.Settings.PassphraseCacheTTL); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, entry := range p.Settings.keys {
+		key, err := p.setupKey(ctx, gpgclient, entry)
+		if err != nil {
+			return err
+		}
+
+		p.Settings.signingKeys = append(p.Settings.signingKeys, key)
+
+		if i > 0 {
+			log.Info().Str("fingerprint", key.Fingerprint).Msg("add co-signing key")
+		}
+	}
+
+	gpgclient.UseKey(p.Settings.signingKeys[0])
+
+	// Exit early in setup-only mode
+	if p.Settings.setupOnly {
+		if p.Settings.GitConfig == "" {
+			return nil
+		}
+
+		return p.setupGit(ctx, gpgclient)
+	}
+
+	switch p.Settings.Mode {
+	case ModeApt:
+		err = p.signApt(ctx, gpgclient)
+	case ModeRpm:
+		err = p.signRpm(ctx, gpgclient)
+	case ModeHelm:
+		err = p.signHelm(ctx, gpgclient)
+	case ModeTerraform:
+		err = p.signTerraform(ctx, gpgclient)
+	case ModeMaven:
+		err = p.signMaven(ctx, gpgclient)
+	case ModeArch:
+		err = p.signArch(ctx, gpgclient)
+	case ModeGit:
+		err = p.signGit(ctx, gpgclient)
+	default:
+		err = p.sign(ctx, gpgclient)
+	}
+
+	// The report is written on failures as well to record the files signed so far
 	if err != nil {
+		p.Settings.report.setError(err)
+
+		if reportErr := p.writeReport(); reportErr != nil {
+			log.Warn().Msgf("failed to write signing report: %s", reportErr)
+		}
+
 		return err
 	}
 
-	log.Info().Msgf("read private key and environment metadata")
+	return p.writeReport()
+}
 
-	fmt.Print(
-		"GnuPG info\n",
-		fmt.Sprintf("Version    : %s (libgcrypt %s)\n", version.Gnupg, version.Libgcrypt),
-		fmt.Sprintf("Libdir     : %s\n", gpgclient.Dirs.Lib),
-		fmt.Sprintf("Libexecdir : %s\n", gpgclient.Dirs.Libexec),
-		fmt.Sprintf("Datadir    : %s\n", gpgclient.Dirs.Data),
-		fmt.Sprintf("Homedir    : %s\n", gpgclient.Dirs.Home),
-		"\n",
-	)
+// setupKey reads the private key of the given entry, prints the key info and imports
+// it into the keyring of the gpg backend. The key with the resolved metadata is returned.
+func (p *Plugin) setupKey(ctx context.Context, gpgclient *gnupg.Client, entry KeyEntry) (gnupg.Key, error) {
+	gpgclient.UseKey(gnupg.Key{Content: entry.Key, Passphrase: entry.Passphrase})
 
 	// Read key
 	if err := gpgclient.ReadPrivateKey(); err != nil {
-		return err
+		return gnupg.Key{}, err
+	}
+
+	// Use the configured fingerprint if it is capable of signing, or the newest signing subkey
+	subkey, err := gpgclient.Key.NewestSigningSubkey()
+	if entry.Fingerprint != "" {
+		subkey, err = gpgclient.Key.SigningSubkey(entry.Fingerprint)
+	}
+
+	if err != nil {
+		return gnupg.Key{}, err
+	}
+
+	gpgclient.Key.Fingerprint = subkey.Fingerprint
+
+	// Check the key health before any signature is created
+	healthErr := gpgclient.CheckKeyHealth(p.keyPolicy())
+
+	printKeyInfo(gpgclient.Key)
+
+	for _, warning := range gpgclient.Key.Health.Warnings {
+		log.Warn().Str("fingerprint", gpgclient.Key.Fingerprint).Msg(warning)
+	}
+
+	if healthErr != nil {
+		return gnupg.Key{}, healthErr
+	}
+
+	if p.Settings.RequireEmail != "" {
+		if err := gpgclient.Key.RequireEmail(p.Settings.RequireEmail); err != nil {
+			return gnupg.Key{}, err
+		}
+	}
+
+	log.Info().Str("fingerprint", gpgclient.Key.Fingerprint).
+		Msg("use fingerprint")
+
+	// The native backend signs in-process and does not need a keyring
+	if gpgclient.Backend == gnupg.BackendGPG {
+		// Import key
+		log.Info().Msg("import private key")
+
+		if err := gpgclient.ImportKey(ctx); err != nil {
+			return gnupg.Key{}, err
+		}
+
+		// Set key owner trust
+		log.Info().Str("trustlevel", p.Settings.TrustLevel).
+			Msg("set key owner trust")
+
+		if err := gpgclient.SetTrustLevel(ctx, p.Settings.TrustLevel); err != nil {
+			return gnupg.Key{}, err
+		}
+
+		// Preset the passphrase once instead of passing it on every signing call
+		if p.Settings.PresetPassphrase && gpgclient.Key.Passphrase != "" {
+			log.Info().Msg("preset passphrase in gpg-agent")
+
+			if err := gpgclient.PresetPassphrase(ctx); err != nil {
+				return gnupg.Key{}, err
+			}
+		}
+	}
+
+	return gpgclient.Key, nil
+}
+
+// keyPolicy returns the key health policy from the plugin settings.
+func (p *Plugin) keyPolicy() gnupg.KeyPolicy {
+	return gnupg.KeyPolicy{
+		ExpiryWarn: p.Settings.KeyExpiryWarn,
+		ExpiryFail: p.Settings.KeyExpiryFail,
+		MinRSABits: p.Settings.KeyMinRSABits,
+		Algorithms: p.Settings.KeyAlgorithms,
+	}
+}
+
+// printKeyInfo prints the metadata, user IDs, subkeys and health of the given key.
+func printKeyInfo(key gnupg.Key) {
+	expires := "never"
+	if !key.Health.ExpirationTime.IsZero() {
+		expires = key.Health.ExpirationTime.String()
 	}
 
 	fmt.Print(
 		"GPG private key info\n",
-		fmt.Sprintf("Fingerprint  : %s\n", gpgclient.Key.Fingerprint),
-		fmt.Sprintf("KeyID        : %s\n", gpgclient.Key.ID),
-		fmt.Sprintf("Identity     : %s\n", gpgclient.Key.Identity),
-		fmt.Sprintf("CreationTime : %s\n", gpgclient.Key.CreationTime),
+		fmt.Sprintf("Fingerprint  : %s\n", key.Fingerprint),
+		fmt.Sprintf("KeyID        : %s\n", key.ID),
+		fmt.Sprintf("Identity     : %s\n", key.Identity),
+		fmt.Sprintf("CreationTime : %s\n", key.CreationTime),
+		fmt.Sprintf("Expires      : %s\n", expires),
+		fmt.Sprintf("Algorithm    : %s\n", key.Health.Algorithm),
+		fmt.Sprintf("Health       : %s\n", key.Health.Status()),
 	)
 
-	if p.Settings.Fingerprint != "" {
-		gpgclient.Key.Fingerprint = p.Settings.Fingerprint
+	for _, uid := range key.UserIDs {
+		expires := "never"
+		if !uid.ExpirationTime.IsZero() {
+			expires = uid.ExpirationTime.String()
+		}
+
+		primary := ""
+		if uid.Primary {
+			primary = "primary, "
+		}
+
+		fmt.Printf(
+			"UserID       : %s created %s expires %s (%s%s)\n",
+			uid.ID, uid.CreationTime, expires, primary, uid.State(),
+		)
 	}
 
-	log.Info().Str("fingerprint", gpgclient.Key.Fingerprint).
-		Msg("use fingerprint")
+	for _, subkey := range key.Subkeys {
+		expires := "never"
+		if !subkey.ExpirationTime.IsZero() {
+			expires = subkey.ExpirationTime.String()
+		}
 
-	// Import key
-	log.Info().Msg("import private key")
+		fmt.Printf(
+			"Subkey       : %s [%s] %s created %s expires %s (%s)\n",
+			subkey.Fingerprint, subkey.Capabilities(), subkey.PolicyName(), subkey.CreationTime, expires, subkey.State(),
+		)
+	}
+}
 
-	if err := gpgclient.ImportKey(); err != nil {
-		return err
+// sign signs all given files and creates the checksum files if configured.
+func (p *Plugin) sign(ctx context.Context, gpgclient *gnupg.Client) error {
+	files := p.withoutExcludes(p.Settings.files)
+
+	// Create and sign checksum files
+	if len(p.Settings.Checksums) > 0 {
+		files = p.withoutChecksumFiles(files)
+
+		if err := p.signChecksums(ctx, gpgclient, files); err != nil {
+			return err
+		}
+
+		if p.Settings.ChecksumOnly {
+			for _, path := range files {
+				p.Settings.report.addSkipped(path, SkipReasonChecksumOnly)
+			}
+
+			return nil
+		}
+	}
+
+	// Sign all given files
+	if len(files) == 0 {
+		return nil
 	}
 
-	// Set key owner trust
-	log.Info().Str("trustlevel", p.Settings.TrustLevel).
-		Msg("set key owner trust")
+	log.Info().Int("concurrency", max(p.Settings.Concurrency, defaultConcurrency)).Msg("sign files")
 
-	if err := gpgclient.SetTrustLevel(p.Settings.TrustLevel); err != nil {
+	return p.signFiles(ctx, gpgclient, files)
+}
+
+// signFile signs the file at the given path with the given signing options and
+// writes the signature to the default location next to the file.
+func (p *Plugin) signFile(
+	ctx context.Context, gpgclient *gnupg.Client, armor, detachSign, clearSign bool, path string,
+) error {
+	output := gnupg.SignaturePath(armor, detachSign, clearSign, path)
+
+	return p.signFileTo(ctx, gpgclient, armor, detachSign, clearSign, path, output)
+}
+
+// signFileTo signs the file at the given path with the given signing options and
+// writes the signature to the output path. If enabled, the created signature is
+// verified right away. The signature is added to the report.
+func (p *Plugin) signFileTo(
+	ctx context.Context, gpgclient *gnupg.Client, armor, detachSign, clearSign bool, path, output string,
+) error {
+	sig, err := gpgclient.SignFileTo(ctx, armor, detachSign, clearSign, path, output)
+	if err != nil {
 		return err
 	}
 
-	// Exit early in setup-only mode
-	if p.Settings.setupOnly {
-		return nil
+	return p.recordSignature(gpgclient, sig, gnupg.SignRequest{
+		Path:       path,
+		Output:     output,
+		Armor:      armor,
+		DetachSign: detachSign,
+		ClearSign:  clearSign,
+	})
+}
+
+// signBatch signs the files of all requests with gnupg.Client.SignFiles, which unlocks
+// the key only once. Every created signature is verified and reported like with
+// signFileTo. Failed files are reported and their errors returned together.
+func (p *Plugin) signBatch(ctx context.Context, gpgclient *gnupg.Client, requests []gnupg.SignRequest) error {
+	results, err := gpgclient.SignFiles(ctx, requests)
+	if err != nil {
+		return err
 	}
 
-	// Sign all given files
-	for i, path := range plugin_slice.SetDifference(p.Settings.files, p.Settings.excludes, true) {
-		if i == 0 {
-			log.Info().Msg("sign files")
+	var errs []error
+
+	for _, res := range results {
+		if res.Err == nil {
+			res.Err = p.recordSignature(gpgclient, res.Signature, res.SignRequest)
 		}
 
-		if err := gpgclient.SignFile(p.Settings.Armor, p.Settings.DetachSign, p.Settings.ClearSign, path); err != nil {
-			return err
+		if res.Err != nil {
+			log.Error().Str("file", res.Path).Err(res.Err).Msg("failed to sign file")
+			p.Settings.report.addFailed(res.Path, res.Err)
+
+			errs = append(errs, fmt.Errorf("%s: %w", res.Path, res.Err))
 		}
 	}
 
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %d of %d files: %w", ErrSignFilesFailed, len(errs), len(requests), errors.Join(errs...))
+	}
+
 	return nil
 }
 
+// recordSignature verifies the signature of the given request if enabled and adds it
+// to the report.
+func (p *Plugin) recordSignature(gpgclient *gnupg.Client, sig gnupg.SignatureCreated, req gnupg.SignRequest) error {
+	if p.Settings.VerifySignatures {
+		if err := gpgclient.VerifySignedFileTo(req.DetachSign, req.ClearSign, req.Path, req.Output); err != nil {
+			return err
+		}
+	}
+
+	return p.Settings.report.addSignature(
+		gpgclient, sig, req.Armor, req.DetachSign, req.ClearSign, req.Path, req.Output,
+	)
+}
+
+// decodeKey returns the given key as armored string. Keys that are not armored
+// are expected to be base64 encoded.
+func decodeKey(key string) (string, error) {
+	if key == "" || gnupg.IsArmored(key) {
+		return key, nil
+	}
+
+	byteKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("not armored but failed to base64 decode: %w", err)
+	}
+
+	return string(byteKey), nil
+}
+
+// readSecretFile returns the content of the secret file at the given path without
+// trailing newlines. The file must not be readable by group or others.
+func readSecretFile(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if fi.Mode().Perm()&secretFilePermMask != 0 {
+		return "", fmt.Errorf("%w: %s: %s", ErrInsecureFilePerm, path, fi.Mode().Perm())
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
 // expandGlobList expands a list of file globs into a list of individual file paths.
-// It filters the results to only include regular files.
-func expandGlobList(fileList []string) ([]string, error) {
+// It filters the results to only include regular files and symlinks to regular files.
+// Dangling symlinks and other file types are returned as skipped files.
+func expandGlobList(fileList []string) ([]string, []string, error) {
 	result := make([]string, 0)
+	skipped := make([]string, 0)
 
 	files, err := plugin_file.ExpandFileList(fileList)
 	if err != nil {
-		return result, err
+		return result, skipped, err
 	}
 
 	for _, f := range files {
-		fs, _ := os.Stat(f)
-		if fs.Mode().IsRegular() {
-			result = append(result, f)
+		fs, err := os.Stat(f)
+		if err != nil {
+			log.Debug().Err(err).Str("file", f).Msg("skip file")
+
+			skipped = append(skipped, f)
+
+			continue
+		}
+
+		if !fs.Mode().IsRegular() {
+			skipped = append(skipped, f)
+
+			continue
 		}
+
+		result = append(result, f)
 	}
 
-	return result, err
+	return result, skipped, nil
 }
@@ -0,0 +1,351 @@
+package plugin
+
+import (
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	openpgp "github.com/ProtonMail/go-crypto/openpgp/v2"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+func TestReadSecretFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "trailing newlines",
+			content: "secret\n\r\n",
+			perm:    0o600,
+			want:    "secret",
+		},
+		{
+			name:    "read only",
+			content: "secret",
+			perm:    0o400,
+			want:    "secret",
+		},
+		{
+			name:    "group readable",
+			content: "secret",
+			perm:    0o640,
+			wantErr: ErrInsecureFilePerm,
+		},
+		{
+			name:    "world readable",
+			content: "secret",
+			perm:    0o604,
+			wantErr: ErrInsecureFilePerm,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			require.NoError(t, os.Chmod(path, tt.perm))
+
+			got, err := readSecretFile(path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlugin_SetupKey(t *testing.T) {
+	key, err := crypto.PGP().KeyGeneration().AddUserId("John Doe", "john.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	entity := key.GetEntity()
+	require.NoError(t, entity.AddSigningSubkey(nil))
+
+	withSubkey, err := crypto.NewKeyFromEntity(entity)
+	require.NoError(t, err)
+
+	armored, err := withSubkey.Armor()
+	require.NoError(t, err)
+
+	primary := strings.ToUpper(key.GetFingerprint())
+	subkey := strings.ToUpper(hex.EncodeToString(entity.Subkeys[1].PublicKey.Fingerprint))
+	encryption := strings.ToUpper(hex.EncodeToString(entity.Subkeys[0].PublicKey.Fingerprint))
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		algorithms  []string
+		email       string
+		want        string
+		wantErr     error
+	}{
+		{
+			name: "newest signing subkey",
+			want: subkey,
+		},
+		{
+			name:       "allowed algorithm",
+			algorithms: []string{"rsa", "ed25519"},
+			want:       subkey,
+		},
+		{
+			name:       "algorithm not allowed",
+			algorithms: []string{"rsa4096", "ed25519"},
+			wantErr:    gnupg.ErrKeyAlgorithmPolicy,
+		},
+		{
+			name:  "required email",
+			email: "john.doe@example.com",
+			want:  subkey,
+		},
+		{
+			name:    "required email missing",
+			email:   "release@example.com",
+			wantErr: gnupg.ErrEmailNotFound,
+		},
+		{
+			name:        "primary key",
+			fingerprint: primary,
+			want:        primary,
+		},
+		{
+			name:        "encryption subkey",
+			fingerprint: encryption,
+			wantErr:     gnupg.ErrKeyCannotSign,
+		},
+		{
+			name:        "foreign fingerprint",
+			fingerprint: "0000000000000000000000000000000000000000",
+			wantErr:     gnupg.ErrFingerprintNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Settings: &Settings{KeyAlgorithms: tt.algorithms, RequireEmail: tt.email}}
+			gpgclient := &gnupg.Client{Backend: gnupg.BackendNative}
+
+			got, err := p.setupKey(t.Context(), gpgclient, KeyEntry{Key: armored, Fingerprint: tt.fingerprint})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Fingerprint)
+		})
+	}
+}
+
+func TestPlugin_SetupKeySignatureTime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	entity, err := openpgp.NewEntity("John Doe", "", "john.doe@example.com", &packet.Config{
+		Time: func() time.Time { return now.Add(-2 * time.Hour) },
+	})
+	require.NoError(t, err)
+	require.NoError(t, entity.AddSigningSubkey(&packet.Config{
+		Time: func() time.Time { return now.Add(-time.Hour) },
+	}))
+
+	key, err := crypto.NewKeyFromEntity(entity)
+	require.NoError(t, err)
+
+	armored, err := key.Armor()
+	require.NoError(t, err)
+
+	primary := strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+	subkey := strings.ToUpper(hex.EncodeToString(entity.Subkeys[len(entity.Subkeys)-1].PublicKey.Fingerprint))
+
+	tests := []struct {
+		name          string
+		signatureTime time.Time
+		fingerprint   string
+		want          string
+		wantErr       error
+	}{
+		{
+			name: "current time",
+			want: subkey,
+		},
+		{
+			name:          "before subkey creation",
+			signatureTime: now.Add(-90 * time.Minute),
+			want:          primary,
+		},
+		{
+			name:          "configured subkey before its creation",
+			signatureTime: now.Add(-90 * time.Minute),
+			fingerprint:   subkey,
+			wantErr:       gnupg.ErrKeyNotYetValid,
+		},
+		{
+			name:          "before key creation",
+			signatureTime: now.Add(-3 * time.Hour),
+			wantErr:       gnupg.ErrKeyNotYetValid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Settings: &Settings{}}
+			gpgclient := &gnupg.Client{Backend: gnupg.BackendNative, SignatureTime: tt.signatureTime}
+
+			got, err := p.setupKey(t.Context(), gpgclient, KeyEntry{Key: armored, Fingerprint: tt.fingerprint})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Fingerprint)
+		})
+	}
+}
+
+func TestPlugin_SetupKeySignatureTimeHealth(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	newKey := func(t *testing.T, created time.Duration, lifetime uint32, revoked time.Duration) string {
+		t.Helper()
+
+		entity, err := openpgp.NewEntity("John Doe", "", "john.doe@example.com", &packet.Config{
+			Time:            func() time.Time { return now.Add(created) },
+			KeyLifetimeSecs: lifetime,
+		})
+		require.NoError(t, err)
+
+		if revoked != 0 {
+			require.NoError(t, entity.Revoke(packet.KeySuperseded, "", &packet.Config{
+				Time: func() time.Time { return now.Add(revoked) },
+			}))
+		}
+
+		key, err := crypto.NewKeyFromEntity(entity)
+		require.NoError(t, err)
+
+		armored, err := key.Armor()
+		require.NoError(t, err)
+
+		return armored
+	}
+
+	tests := []struct {
+		name     string
+		created  time.Duration
+		lifetime uint32
+		revoked  time.Duration
+		settings Settings
+		wantErr  error
+		wantWarn bool
+	}{
+		{
+			name:     "expired now but valid at signature time",
+			created:  -3 * time.Hour,
+			lifetime: 3600,
+			wantErr:  gnupg.ErrKeyExpired,
+		},
+		{
+			name:     "expires within fail window",
+			created:  -3 * time.Hour,
+			lifetime: 4 * 3600,
+			settings: Settings{KeyExpiryFail: 2 * time.Hour},
+			wantErr:  gnupg.ErrKeyExpiresSoon,
+		},
+		{
+			name:     "expires within warn window",
+			created:  -3 * time.Hour,
+			lifetime: 4 * 3600,
+			settings: Settings{KeyExpiryWarn: 2 * time.Hour},
+			wantWarn: true,
+		},
+		{
+			name:    "revoked after signature time",
+			created: -3 * time.Hour,
+			revoked: -time.Hour,
+			wantErr: gnupg.ErrKeyRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Settings: &tt.settings}
+			gpgclient := &gnupg.Client{Backend: gnupg.BackendNative, SignatureTime: now.Add(-150 * time.Minute)}
+
+			got, err := p.setupKey(t.Context(), gpgclient, KeyEntry{Key: newKey(t, tt.created, tt.lifetime, tt.revoked)})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWarn, len(got.Health.Warnings) > 0)
+		})
+	}
+}
+
+func TestPlugin_SetupKeyPresetPassphrase(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	key, err := crypto.PGP().KeyGeneration().AddUserId("John Doe", "john.doe@example.com").New().GenerateKey()
+	require.NoError(t, err)
+
+	locked, err := crypto.PGP().LockKey(key, []byte("secret"))
+	require.NoError(t, err)
+
+	armored, err := locked.Armor()
+	require.NoError(t, err)
+
+	// Keep the default homedir of the client out of the real home directory
+	t.Setenv("HOME", t.TempDir())
+
+	gpgclient, err := gnupg.New("", "")
+	require.NoError(t, err)
+	require.NoError(t, gpgclient.GetDirs(t.Context()))
+	require.NoError(t, gpgclient.ConfigureAgent(t.Context(), time.Hour))
+
+	t.Cleanup(func() {
+		_ = gpgclient.Cleanup(t.Context())
+	})
+
+	p := &Plugin{
+		Settings: &Settings{
+			TrustLevel:       "unknown",
+			PresetPassphrase: true,
+		},
+	}
+
+	_, err = p.setupKey(t.Context(), gpgclient, KeyEntry{Key: armored, Passphrase: "secret"})
+	require.NoError(t, err)
+
+	// Sign without passing the passphrase to gpg
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+
+	gpgclient.Key.Passphrase = ""
+	_, err = gpgclient.SignFileTo(t.Context(), true, true, false, file, file+".asc")
+	require.NoError(t, err)
+
+	assert.NoError(t, gpgclient.VerifySignedFileTo(true, false, file, file+".asc"))
+}
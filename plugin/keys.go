@@ -0,0 +1,184 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var (
+	ErrInvalidMultiSign     = errors.New("invalid multi sign mode")
+	ErrMultipleKeysMode     = errors.New("multiple signing keys are only supported in sign mode")
+	ErrCombinedDetachSign   = errors.New("combined signatures require detach sign")
+	ErrOutputNameMissingKey = errors.New("output name must contain `{{keyid}}` for separate signatures")
+)
+
+const (
+	MultiSignSeparate = "separate"
+	MultiSignCombined = "combined"
+
+	keyIDLength = 16
+)
+
+// KeyEntry holds a private key with the passphrase and fingerprint to use.
+type KeyEntry struct {
+	Key         string `json:"key"`
+	Passphrase  string `json:"passphrase"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// keyEntries returns the signing keys from the single key settings and the given JSON
+// encoded list of additional keys. The keys are decoded with decodeKey.
+func (p *Plugin) keyEntries(raw string) ([]KeyEntry, error) {
+	result := make([]KeyEntry, 0)
+
+	if p.Settings.Key != "" {
+		result = append(result, KeyEntry{
+			Key:         p.Settings.Key,
+			Passphrase:  p.Settings.Passphrase,
+			Fingerprint: p.Settings.Fingerprint,
+		})
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		return result, nil
+	}
+
+	entries := make([]KeyEntry, 0)
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		key, err := decodeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("key %d: %w", i, ErrKeyRequired)
+		}
+
+		entry.Key = key
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// validateKeys validates the settings for signing with multiple keys.
+func (p *Plugin) validateKeys() error {
+	if !slices.Contains([]string{MultiSignSeparate, MultiSignCombined}, p.Settings.MultiSign) {
+		return fmt.Errorf("%w: %s", ErrInvalidMultiSign, p.Settings.MultiSign)
+	}
+
+	if len(p.Settings.keys) <= 1 {
+		return nil
+	}
+
+	if p.Settings.Mode != ModeSign {
+		return ErrMultipleKeysMode
+	}
+
+	switch p.Settings.MultiSign {
+	case MultiSignCombined:
+		if !p.Settings.DetachSign {
+			return ErrCombinedDetachSign
+		}
+	case MultiSignSeparate:
+		if p.Settings.OutputName != "" && !strings.Contains(p.Settings.OutputName, "{{keyid}}") {
+			return ErrOutputNameMissingKey
+		}
+	}
+
+	return nil
+}
+
+// signOutputMulti signs the file at the given path with all signing keys. Depending on
+// the multi sign mode, either a separate signature per key is created or the signatures
+// are combined into a single signature file.
+func (p *Plugin) signOutputMulti(ctx context.Context, gpgclient *gnupg.Client, path string) error {
+	defer gpgclient.UseKey(p.Settings.signingKeys[0])
+
+	if p.Settings.MultiSign == MultiSignSeparate {
+		for _, key := range p.Settings.signingKeys {
+			gpgclient.UseKey(key)
+
+			output, err := p.outputPath(path, keyID(key))
+			if err != nil {
+				return err
+			}
+
+			if err := p.signTo(ctx, gpgclient, path, output); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	output, err := p.outputPath(path, "")
+	if err != nil {
+		return err
+	}
+
+	tmpdir, err := os.MkdirTemp("", "wp-gpgsign-multi-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	signatures := make([]string, 0, len(p.Settings.signingKeys))
+
+	for _, key := range p.Settings.signingKeys {
+		gpgclient.UseKey(key)
+
+		sig := filepath.Join(tmpdir, keyID(key)+".sig")
+
+		if err := p.signFileTo(ctx, gpgclient, false, true, false, path, sig); err != nil {
+			return err
+		}
+
+		signatures = append(signatures, sig)
+	}
+
+	gnupg.Logger(ctx).Info().Str("file", output).Int("signatures", len(signatures)).Msg("combine signatures")
+
+	if err := os.MkdirAll(filepath.Dir(output), outputDirPerm); err != nil {
+		return fmt.Errorf("failed to create output dir: %w", err)
+	}
+
+	if err := gnupg.CombineDetachedSignatures(p.Settings.Armor, output, signatures...); err != nil {
+		return err
+	}
+
+	return p.Settings.report.updateSignatures(signatures, func(entry *ReportSignature) error {
+		entry.Signature = output
+		entry.Armor = p.Settings.Armor
+
+		return nil
+	})
+}
+
+// keyID returns the long key ID of the signing key, which are the last 16 characters
+// of the fingerprint.
+func keyID(key gnupg.Key) string {
+	fingerprint := strings.ToUpper(key.Fingerprint)
+	if len(fingerprint) <= keyIDLength {
+		return fingerprint
+	}
+
+	return fingerprint[len(fingerprint)-keyIDLength:]
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var ErrNoMavenArtifacts = errors.New("no maven artifacts found")
+
+const (
+	mavenSignatureExt = ".asc"
+	mavenChecksumPerm = 0o644
+)
+
+// signMaven signs all artifacts of the Maven repository layout in the repository root
+// with an armored detached `.asc` signature and writes `.md5`, `.sha1`, `.sha256` and
+// `.sha512` checksum files for every artifact and signature. If configured, only these
+// files are packed into a zip bundle for the Central Portal upload.
+func (p *Plugin) signMaven(ctx context.Context, gpgclient *gnupg.Client) error {
+	artifacts, err := mavenArtifacts(p.Settings.RepositoryRoot)
+	if err != nil {
+		return fmt.Errorf("failed to find maven artifacts: %w", err)
+	}
+
+	artifacts = p.withoutExcludes(artifacts)
+	if len(artifacts) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoMavenArtifacts, p.Settings.RepositoryRoot)
+	}
+
+	requests := make([]gnupg.SignRequest, 0, len(artifacts))
+
+	for _, path := range artifacts {
+		log.Info().Str("file", path).Msg("sign maven artifact")
+
+		requests = append(requests, gnupg.SignRequest{
+			Path:       path,
+			Output:     path + mavenSignatureExt,
+			Armor:      true,
+			DetachSign: true,
+		})
+	}
+
+	if err := p.signBatch(ctx, gpgclient, requests); err != nil {
+		return err
+	}
+
+	bundled := make([]string, 0)
+
+	for _, path := range artifacts {
+		for _, file := range []string{path, path + mavenSignatureExt} {
+			sidecars, err := writeChecksumSidecars(file)
+			if err != nil {
+				return err
+			}
+
+			bundled = append(bundled, file)
+			bundled = append(bundled, sidecars...)
+		}
+	}
+
+	if p.Settings.MavenBundle == "" {
+		return nil
+	}
+
+	log.Info().Str("file", p.Settings.MavenBundle).Int("files", len(bundled)).Msg("create maven bundle")
+
+	return writeMavenBundle(p.Settings.RepositoryRoot, p.Settings.MavenBundle, bundled)
+}
+
+// mavenArtifacts returns all artifacts below the root directory that need to be signed.
+func mavenArtifacts(root string) ([]string, error) {
+	result := make([]string, 0)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() && slices.Contains([]string{".jar", ".pom", ".module"}, filepath.Ext(path)) {
+			result = append(result, path)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// writeChecksumSidecars writes a `<file>.<algorithm>` checksum file for every checksum
+// algorithm required by Maven Central. The files only contain the hex encoded hash. The
+// paths of the written files are returned.
+func writeChecksumSidecars(path string) ([]string, error) {
+	algorithms := []string{"md5", "sha1", "sha256", "sha512"}
+	sidecars := make([]string, 0, len(algorithms))
+
+	for _, algorithm := range algorithms {
+		sum, err := fileChecksum(path, algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash file %s: %w", path, err)
+		}
+
+		sidecar := path + "." + algorithm
+		if err := os.WriteFile(sidecar, []byte(sum), mavenChecksumPerm); err != nil {
+			return nil, fmt.Errorf("failed to write checksum file %s: %w", sidecar, err)
+		}
+
+		sidecars = append(sidecars, sidecar)
+	}
+
+	return sidecars, nil
+}
+
+// writeMavenBundle packs the given files into a zip archive at the given path. The paths
+// in the archive are relative to the root directory and sorted.
+func writeMavenBundle(root, path string, files []string) error {
+	names := make(map[string]string, len(files))
+
+	for _, file := range files {
+		name, err := filepath.Rel(root, file)
+		if err != nil {
+			return fmt.Errorf("failed to create maven bundle %s: %w", path, err)
+		}
+
+		names[filepath.ToSlash(name)] = file
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create maven bundle %s: %w", path, err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		if err := addZipFile(zw, names[name], name); err != nil {
+			return fmt.Errorf("failed to create maven bundle %s: %w", path, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// addZipFile adds the file at the given path to the zip archive.
+func addZipFile(zw *zip.Writer, path, name string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+
+	return err
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_SignMaven(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts []string
+		bundle    bool
+		wantErr   error
+	}{
+		{
+			name: "artifacts",
+			artifacts: []string{
+				"com/example/demo/1.0.0/demo-1.0.0.jar",
+				"com/example/demo/1.0.0/demo-1.0.0.pom",
+				"com/example/demo/1.0.0/demo-1.0.0.module",
+			},
+		},
+		{
+			name:      "artifacts with bundle",
+			artifacts: []string{"com/example/demo/1.0.0/demo-1.0.0.pom"},
+			bundle:    true,
+		},
+		{
+			name:    "no artifacts",
+			wantErr: ErrNoMavenArtifacts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			for _, artifact := range tt.artifacts {
+				path := filepath.Join(root, artifact)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(artifact), 0o600))
+			}
+
+			// Unrelated files of the workspace must not be bundled
+			require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("demo"), 0o600))
+
+			p := &Plugin{
+				Settings: &Settings{
+					RepositoryRoot:   root,
+					VerifySignatures: true,
+				},
+			}
+
+			if tt.bundle {
+				p.Settings.MavenBundle = filepath.Join(root, "bundle.zip")
+			}
+
+			gpgclient := newTestClient(t)
+
+			err := p.signMaven(t.Context(), gpgclient)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			want := make([]string, 0)
+
+			for _, artifact := range tt.artifacts {
+				for _, file := range []string{artifact, artifact + ".asc"} {
+					want = append(want, file)
+
+					for _, algorithm := range []string{"md5", "sha1", "sha256", "sha512"} {
+						want = append(want, file+"."+algorithm)
+
+						sum, err := fileChecksum(filepath.Join(root, file), algorithm)
+						require.NoError(t, err)
+
+						got, err := os.ReadFile(filepath.Join(root, file+"."+algorithm))
+						require.NoError(t, err)
+						assert.Equal(t, sum, string(got))
+					}
+				}
+			}
+
+			// A second run must not sign the generated files again.
+			require.NoError(t, p.signMaven(t.Context(), gpgclient))
+			assert.NoFileExists(t, filepath.Join(root, tt.artifacts[0]+".asc.asc"))
+
+			if !tt.bundle {
+				return
+			}
+
+			zr, err := zip.OpenReader(p.Settings.MavenBundle)
+			require.NoError(t, err)
+
+			defer zr.Close()
+
+			names := make([]string, 0)
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+			}
+
+			slices.Sort(want)
+			assert.Equal(t, want, names)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var ErrOutsideOutputBase = errors.New("file is outside of the output base directory")
+
+const outputDirPerm = 0o755
+
+// signOutput signs the file at the given path with the signing options from the
+// settings and writes the signature to the configured output location. If multiple
+// signing keys are configured, the file is signed with all of them.
+func (p *Plugin) signOutput(ctx context.Context, gpgclient *gnupg.Client, path string) error {
+	if len(p.Settings.signingKeys) > 1 {
+		return p.signOutputMulti(ctx, gpgclient, path)
+	}
+
+	output, err := p.outputPath(path, "")
+	if err != nil {
+		return err
+	}
+
+	return p.signTo(ctx, gpgclient, path, output)
+}
+
+// signTo signs the file at the given path with the signing options from the settings
+// and writes the signature to the given output path.
+func (p *Plugin) signTo(ctx context.Context, gpgclient *gnupg.Client, path, output string) error {
+	if err := os.MkdirAll(filepath.Dir(output), outputDirPerm); err != nil {
+		return fmt.Errorf("failed to create output dir: %w", err)
+	}
+
+	return p.signFileTo(ctx, gpgclient, p.Settings.Armor, p.Settings.DetachSign, p.Settings.ClearSign, path, output)
+}
+
+// outputPath returns the path of the signature for the file at the given path. By
+// default, the signature is placed next to the file with the gpg default naming. If
+// an output directory is configured, the directory structure of the file relative to
+// the output base directory is mirrored into it. If an output name template is
+// configured, it is used for the file name of the signature. If a key ID is given, it is
+// added to the default file name before the signature extension.
+func (p *Plugin) outputPath(path, keyID string) (string, error) {
+	name := filepath.Base(gnupg.SignaturePath(p.Settings.Armor, p.Settings.DetachSign, p.Settings.ClearSign, path))
+
+	switch {
+	case p.Settings.OutputName != "":
+		name = outputName(p.Settings.OutputName, path, keyID)
+	case keyID != "":
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "." + keyID + ext
+	}
+
+	dir := filepath.Dir(path)
+	if p.Settings.OutputDir == "" {
+		return filepath.Join(dir, name), nil
+	}
+
+	base, err := filepath.Abs(p.Settings.OutputBase)
+	if err != nil {
+		return "", err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(base, absDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideOutputBase, path)
+	}
+
+	return filepath.Join(p.Settings.OutputDir, rel, name), nil
+}
+
+// outputName renders the output name template for the file at the given path. The
+// placeholder `{{name}}` is replaced by the file name without the last extension,
+// `{{ext}}` by the last extension without the leading dot and `{{keyid}}` by the given
+// key ID. If the file has no extension, `.{{ext}}` is removed.
+func outputName(tmpl, path, keyID string) string {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+
+	if ext == "" {
+		tmpl = strings.ReplaceAll(tmpl, ".{{ext}}", "")
+	}
+
+	return strings.NewReplacer(
+		"{{name}}", name,
+		"{{ext}}", strings.TrimPrefix(ext, "."),
+		"{{keyid}}", keyID,
+	).Replace(tmpl)
+}
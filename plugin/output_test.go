@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputName(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		path string
+		want string
+	}{
+		{
+			tmpl: "{{name}}.{{ext}}.minisig-compatible.asc",
+			path: "dist/app.tar.gz",
+			want: "app.tar.gz.minisig-compatible.asc",
+		},
+		{tmpl: "{{name}}.{{ext}}.sig", path: "dist/app", want: "app.sig"},
+		{tmpl: "{{name}}-signature.asc", path: "dist/app.zip", want: "app-signature.asc"},
+		{tmpl: "{{name}}.{{ext}}.{{keyid}}.sig", path: "dist/app.zip", want: "app.zip.0123456789ABCDEF.sig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			assert.Equal(t, tt.want, outputName(tt.tmpl, tt.path, "0123456789ABCDEF"))
+		})
+	}
+}
+
+func TestPlugin_OutputPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		path     string
+		keyID    string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "default",
+			settings: Settings{Armor: true, DetachSign: true},
+			path:     filepath.Join("dist", "app.zip"),
+			want:     filepath.Join("dist", "app.zip.asc"),
+		},
+		{
+			name:     "output dir",
+			settings: Settings{DetachSign: true, OutputDir: "signatures", OutputBase: "dist"},
+			path:     filepath.Join("dist", "linux", "app.zip"),
+			want:     filepath.Join("signatures", "linux", "app.zip.sig"),
+		},
+		{
+			name: "output dir with name template",
+			settings: Settings{
+				Armor: true, DetachSign: true,
+				OutputDir: "signatures", OutputBase: ".", OutputName: "{{name}}.{{ext}}.minisig-compatible.asc",
+			},
+			path: filepath.Join("dist", "app.zip"),
+			want: filepath.Join("signatures", "dist", "app.zip.minisig-compatible.asc"),
+		},
+		{
+			name:     "key id",
+			settings: Settings{Armor: true, DetachSign: true},
+			path:     filepath.Join("dist", "app.zip"),
+			keyID:    "0123456789ABCDEF",
+			want:     filepath.Join("dist", "app.zip.0123456789ABCDEF.asc"),
+		},
+		{
+			name:     "outside of output base",
+			settings: Settings{DetachSign: true, OutputDir: "signatures", OutputBase: "dist"},
+			path:     filepath.Join("other", "app.zip"),
+			wantErr:  ErrOutsideOutputBase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Settings: &tt.settings}
+
+			got, err := p.outputPath(tt.path, tt.keyID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
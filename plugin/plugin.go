@@ -7,7 +7,9 @@ package plugin
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
 	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
 	"github.com/urfave/cli/v3"
 )
@@ -22,6 +24,8 @@ type Plugin struct {
 
 // Settings for the plugin.
 type Settings struct {
+	Mode        string
+	Backend     string
 	Homedir     string
 	Key         string
 	Passphrase  string
@@ -30,10 +34,55 @@ type Settings struct {
 	DetachSign  bool
 	ClearSign   bool
 	TrustLevel  string
+	GitConfig   string
+	MultiSign   string
 
-	setupOnly bool
-	files     []string
-	excludes  []string
+	KeyFile        string
+	PassphraseFile string
+
+	PresetPassphrase   bool
+	PassphraseCacheTTL time.Duration
+	CommandTimeout     time.Duration
+
+	KeyExpiryWarn time.Duration
+	KeyExpiryFail time.Duration
+	KeyMinRSABits int
+	KeyAlgorithms []string
+	RequireEmail  string
+
+	GitTag        string
+	GitCommit     string
+	GitTagMessage string
+	GitArchive    string
+
+	VerifySignatures bool
+	SignatureTime    string
+	Concurrency      int
+
+	ReportFile   string
+	ReportDotenv string
+
+	OutputDir  string
+	OutputBase string
+	OutputName string
+
+	PublicKey          string
+	VerifyFingerprints []string
+
+	RepositoryRoot string
+	ExportKey      string
+	MavenBundle    string
+
+	Checksums    []string
+	ChecksumFile string
+	ChecksumOnly bool
+
+	setupOnly   bool
+	files       []string
+	excludes    []string
+	keys        []KeyEntry
+	signingKeys []gnupg.Key
+	report      *Report
 }
 
 func New(e plugin_base.ExecuteFunc, build ...string) *Plugin {
@@ -69,6 +118,22 @@ func New(e plugin_base.ExecuteFunc, build ...string) *Plugin {
 // Flags returns a slice of CLI flags for the plugin.
 func Flags(settings *Settings, category string) []cli.Flag {
 	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "mode",
+			Usage:       "plugin mode",
+			Sources:     cli.EnvVars("PLUGIN_MODE"),
+			Destination: &settings.Mode,
+			Value:       ModeSign,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "backend",
+			Usage:       "signing backend to use",
+			Sources:     cli.EnvVars("PLUGIN_BACKEND"),
+			Destination: &settings.Backend,
+			Value:       gnupg.BackendGPG,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "homedir",
 			Usage:       "gpg home directory",
@@ -80,7 +145,6 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Name:     "key",
 			Usage:    "armored private gpg private key or the base64 encoded string of it",
 			Sources:  cli.EnvVars("PLUGIN_KEY", "GPGSIGN_KEY", "GPG_KEY"),
-			Required: true,
 			Category: category,
 		},
 		&cli.StringFlag{
@@ -90,6 +154,42 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Passphrase,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "key-file",
+			Usage:       "path to a file containing the armored or base64 encoded private gpg key, takes precedence over key",
+			Sources:     cli.EnvVars("PLUGIN_KEY_FILE", "GPGSIGN_KEY_FILE", "GPG_KEY_FILE"),
+			Destination: &settings.KeyFile,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "passphrase-file",
+			Usage:       "path to a file containing the passphrase for the gpg private key, takes precedence over passphrase",
+			Sources:     cli.EnvVars("PLUGIN_PASSPHRASE_FILE", "GPGSIGN_PASSPHRASE_FILE", "GPG_PASSPHRASE_FILE"),
+			Destination: &settings.PassphraseFile,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "preset-passphrase",
+			Usage:       "preset the passphrase in the gpg-agent once instead of passing it on every signing call",
+			Sources:     cli.EnvVars("PLUGIN_PRESET_PASSPHRASE"),
+			Destination: &settings.PresetPassphrase,
+			Category:    category,
+		},
+		&cli.DurationFlag{
+			Name:        "passphrase-cache-ttl",
+			Usage:       "cache ttl of the gpg-agent, does not apply to preset passphrases",
+			Sources:     cli.EnvVars("PLUGIN_PASSPHRASE_CACHE_TTL"),
+			Destination: &settings.PassphraseCacheTTL,
+			Value:       defaultPassphraseCacheTTL,
+			Category:    category,
+		},
+		&cli.DurationFlag{
+			Name:        "command-timeout",
+			Usage:       "timeout of every gpg and git command",
+			Sources:     cli.EnvVars("PLUGIN_COMMAND_TIMEOUT"),
+			Destination: &settings.CommandTimeout,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "fingerprint",
 			Usage:       "specific fingerprint to be used (subkey)",
@@ -97,6 +197,20 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Fingerprint,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:     "keys",
+			Usage:    "json list of additional signing keys with `key`, `passphrase` and `fingerprint` entries",
+			Sources:  cli.EnvVars("PLUGIN_KEYS"),
+			Category: category,
+		},
+		&cli.StringFlag{
+			Name:        "multi-sign",
+			Usage:       "create a `separate` signature per key or a single `combined` signature for multiple keys",
+			Sources:     cli.EnvVars("PLUGIN_MULTI_SIGN"),
+			Destination: &settings.MultiSign,
+			Value:       MultiSignSeparate,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "trust-level",
 			Usage:       "key owner trust level",
@@ -105,6 +219,78 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Value:       "unknown",
 			Category:    category,
 		},
+		&cli.DurationFlag{
+			Name:        "key-expiry-warn",
+			Usage:       "warn if the signing key expires within the given duration",
+			Sources:     cli.EnvVars("PLUGIN_KEY_EXPIRY_WARN"),
+			Destination: &settings.KeyExpiryWarn,
+			Value:       defaultKeyExpiryWarn,
+			Category:    category,
+		},
+		&cli.DurationFlag{
+			Name:        "key-expiry-fail",
+			Usage:       "fail if the signing key expires within the given duration",
+			Sources:     cli.EnvVars("PLUGIN_KEY_EXPIRY_FAIL"),
+			Destination: &settings.KeyExpiryFail,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "key-min-rsa-bits",
+			Usage:       "minimum key size of rsa signing keys",
+			Sources:     cli.EnvVars("PLUGIN_KEY_MIN_RSA_BITS"),
+			Destination: &settings.KeyMinRSABits,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "key-algorithms",
+			Usage:       "list of allowed signing key algorithms, e.g. `rsa4096` or `ed25519`",
+			Sources:     cli.EnvVars("PLUGIN_KEY_ALGORITHMS"),
+			Destination: &settings.KeyAlgorithms,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "require-email",
+			Usage:       "email address that must be present on a valid user id of the signing key",
+			Sources:     cli.EnvVars("PLUGIN_REQUIRE_EMAIL"),
+			Destination: &settings.RequireEmail,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "git-config",
+			Usage:       "scope of the git config for commit and tag signing written in setup-only mode (global|local)",
+			Sources:     cli.EnvVars("PLUGIN_GIT_CONFIG"),
+			Destination: &settings.GitConfig,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "git-tag",
+			Usage:       "name of the signed git tag created in git mode",
+			Sources:     cli.EnvVars("PLUGIN_GIT_TAG", "CI_COMMIT_TAG"),
+			Destination: &settings.GitTag,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "git-commit",
+			Usage:       "commit to create the signed git tag for in git mode",
+			Sources:     cli.EnvVars("PLUGIN_GIT_COMMIT", "CI_COMMIT_SHA"),
+			Destination: &settings.GitCommit,
+			Value:       "HEAD",
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "git-tag-message",
+			Usage:       "message of the signed git tag, defaults to the tag name",
+			Sources:     cli.EnvVars("PLUGIN_GIT_TAG_MESSAGE"),
+			Destination: &settings.GitTagMessage,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "git-archive",
+			Usage:       "path of the signed archive of the git tag created in git mode",
+			Sources:     cli.EnvVars("PLUGIN_GIT_ARCHIVE"),
+			Destination: &settings.GitArchive,
+			Category:    category,
+		},
 		&cli.BoolFlag{
 			Name:        "armor",
 			Usage:       "create ASCII-armored output instead of a binary",
@@ -127,6 +313,42 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.ClearSign,
 			Category:    category,
 		},
+		&cli.BoolFlag{
+			Name:        "verify-signatures",
+			Usage:       "verify every created signature against the public part of the signing key",
+			Sources:     cli.EnvVars("PLUGIN_VERIFY_SIGNATURES"),
+			Destination: &settings.VerifySignatures,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "signature-time",
+			Usage:       "pin the signature creation time to `source-date-epoch`, `commit` or an RFC3339 timestamp",
+			Sources:     cli.EnvVars("PLUGIN_SIGNATURE_TIME"),
+			Destination: &settings.SignatureTime,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "concurrency",
+			Usage:       "number of files signed in parallel",
+			Sources:     cli.EnvVars("PLUGIN_CONCURRENCY"),
+			Destination: &settings.Concurrency,
+			Value:       defaultConcurrency,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "report-file",
+			Usage:       "path of the json report of all created signatures and skipped files",
+			Sources:     cli.EnvVars("PLUGIN_REPORT_FILE"),
+			Destination: &settings.ReportFile,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "report-dotenv",
+			Usage:       "path of the dotenv summary of all created signatures and skipped files",
+			Sources:     cli.EnvVars("PLUGIN_REPORT_DOTENV"),
+			Destination: &settings.ReportDotenv,
+			Category:    category,
+		},
 		&cli.StringSliceFlag{
 			Name:     "files",
 			Usage:    "list of glob patterns to determine files to be signed",
@@ -139,5 +361,84 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Sources:  cli.EnvVars("PLUGIN_EXCLUDES", "PLUGIN_EXCLUDE"),
 			Category: category,
 		},
+		&cli.StringFlag{
+			Name:        "output-dir",
+			Usage:       "directory to write the signatures to, the tree relative to the output base is mirrored",
+			Sources:     cli.EnvVars("PLUGIN_OUTPUT_DIR"),
+			Destination: &settings.OutputDir,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "output-base",
+			Usage:       "base directory of the files whose tree is mirrored into the output dir",
+			Sources:     cli.EnvVars("PLUGIN_OUTPUT_BASE"),
+			Destination: &settings.OutputBase,
+			Value:       ".",
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "output-name",
+			Usage:       "file name template of the signatures, supports `{{name}}` and `{{ext}}` placeholders",
+			Sources:     cli.EnvVars("PLUGIN_OUTPUT_NAME"),
+			Destination: &settings.OutputName,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "repository-root",
+			Usage:       "root directory of the package repository to sign",
+			Sources:     cli.EnvVars("PLUGIN_REPOSITORY_ROOT"),
+			Destination: &settings.RepositoryRoot,
+			Value:       ".",
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "export-key",
+			Usage:       "path relative to the repository root to export the armored public key to",
+			Sources:     cli.EnvVars("PLUGIN_EXPORT_KEY"),
+			Destination: &settings.ExportKey,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "maven-bundle",
+			Usage:       "path of the zip bundle for the maven central portal upload created in maven mode",
+			Sources:     cli.EnvVars("PLUGIN_MAVEN_BUNDLE"),
+			Destination: &settings.MavenBundle,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:     "public-key",
+			Usage:    "armored public gpg keys used for verification or the base64 encoded string of it",
+			Sources:  cli.EnvVars("PLUGIN_PUBLIC_KEY", "GPGSIGN_PUBLIC_KEY", "GPG_PUBLIC_KEY"),
+			Category: category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "verify-fingerprints",
+			Usage:       "list of fingerprints that are accepted as signers in verify mode",
+			Sources:     cli.EnvVars("PLUGIN_VERIFY_FINGERPRINTS", "PLUGIN_VERIFY_FINGERPRINT"),
+			Destination: &settings.VerifyFingerprints,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "checksums",
+			Usage:       "list of hash algorithms to create signed checksum files for",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUMS", "PLUGIN_CHECKSUM"),
+			Destination: &settings.Checksums,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "checksum-file",
+			Usage:       "path of the checksum file, `{{algorithm}}` is replaced by the upper case algorithm name",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_FILE"),
+			Destination: &settings.ChecksumFile,
+			Value:       "{{algorithm}}SUMS",
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "checksum-only",
+			Usage:       "sign the checksum files only and skip signing the individual files",
+			Sources:     cli.EnvVars("PLUGIN_CHECKSUM_ONLY"),
+			Destination: &settings.ChecksumOnly,
+			Category:    category,
+		},
 	}
 }
@@ -0,0 +1,325 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+	plugin_slice "github.com/thegeeklab/wp-plugin-go/v6/slice"
+)
+
+const (
+	reportFilePerm = 0o644
+
+	SkipReasonExcluded     = "excluded"
+	SkipReasonNotRegular   = "not a regular file"
+	SkipReasonChecksumFile = "generated checksum file"
+	SkipReasonChecksumOnly = "checksum only"
+	SkipReasonUnsupported  = "unsupported file type"
+)
+
+// Report is the machine-readable record of a plugin run. If the run failed, the error
+// is recorded together with the files that could not be signed.
+type Report struct {
+	Mode       string            `json:"mode"`
+	Backend    string            `json:"backend"`
+	Signatures []ReportSignature `json:"signatures"`
+	Skipped    []ReportSkipped   `json:"skipped"`
+	Failed     []ReportFailed    `json:"failed"`
+	Error      string            `json:"error,omitempty"`
+
+	mu sync.Mutex
+}
+
+// ReportSignature describes a created signature and the signed file.
+type ReportSignature struct {
+	File        string              `json:"file"`
+	Size        int64               `json:"size"`
+	SHA256      string              `json:"sha256"`
+	Signature   string              `json:"signature"`
+	Type        gnupg.SignatureType `json:"type"`
+	Armor       bool                `json:"armor"`
+	Fingerprint string              `json:"fingerprint"`
+	KeyID       string              `json:"key_id"`
+	Timestamp   time.Time           `json:"timestamp"`
+}
+
+// ReportSkipped describes a file that was not signed.
+type ReportSkipped struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// ReportFailed describes a file that could not be signed.
+type ReportFailed struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+func newReport(mode, backend string) *Report {
+	return &Report{
+		Mode:       mode,
+		Backend:    backend,
+		Signatures: make([]ReportSignature, 0),
+		Skipped:    make([]ReportSkipped, 0),
+		Failed:     make([]ReportFailed, 0),
+	}
+}
+
+// addSignature adds the given signature of the file at the given path created by the
+// current key of the client. The creation time and the signing key are taken from the
+// signature, or from the client if the backend did not report them. Nothing is recorded
+// if the report is disabled.
+func (r *Report) addSignature(
+	gpgclient *gnupg.Client, sig gnupg.SignatureCreated, armor, detachSign, clearSign bool, path, output string,
+) error {
+	if r == nil {
+		return nil
+	}
+
+	entry := ReportSignature{
+		Signature:   output,
+		Armor:       armor,
+		Fingerprint: gpgclient.Key.Fingerprint,
+		KeyID:       keyID(gpgclient.Key),
+		Timestamp:   sig.Timestamp,
+	}
+
+	if sig.Fingerprint != "" {
+		entry.Fingerprint = sig.Fingerprint
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = gpgclient.SignatureTime
+	}
+
+	switch {
+	case detachSign:
+		entry.Type = gnupg.SignatureDetached
+	case clearSign:
+		entry.Type = gnupg.SignatureCleartext
+		entry.Armor = true
+	default:
+		entry.Type = gnupg.SignatureInline
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC().Truncate(time.Second)
+	}
+
+	if err := entry.setFile(path); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Signatures = append(r.Signatures, entry)
+
+	return nil
+}
+
+// updateSignatures applies the given function to all entries of the given signature files.
+// It is used if a signature is not written to its final location or the signed file is
+// only an intermediate representation of the actual input file.
+func (r *Report) updateSignatures(signatures []string, fn func(*ReportSignature) error) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.Signatures {
+		if !slices.Contains(signatures, r.Signatures[i].Signature) {
+			continue
+		}
+
+		if err := fn(&r.Signatures[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addFailed adds a file that could not be signed with the given error.
+func (r *Report) addFailed(path string, err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Failed = append(r.Failed, ReportFailed{File: path, Error: err.Error()})
+}
+
+// setError records the error of a failed run.
+func (r *Report) setError(err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Error = err.Error()
+}
+
+// addSkipped adds a file that was not signed with the given reason.
+func (r *Report) addSkipped(path, reason string) {
+	if r == nil {
+		return
+	}
+
+	log.Debug().Str("file", path).Str("reason", reason).Msg("skip file")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Skipped = append(r.Skipped, ReportSkipped{File: path, Reason: reason})
+}
+
+// setFile sets the signed file with its size and checksum.
+func (e *ReportSignature) setFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read signed file: %w", err)
+	}
+
+	sum, err := fileChecksum(path, "sha256")
+	if err != nil {
+		return err
+	}
+
+	e.File = path
+	e.Size = fi.Size()
+	e.SHA256 = sum
+
+	return nil
+}
+
+// writeJSON writes the report in JSON format to the given path.
+func (r *Report) writeJSON(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	return writeReportFile(path, append(data, '\n'))
+}
+
+// writeDotenv writes a summary of the report in dotenv format to the given path.
+// List values are separated by commas.
+func (r *Report) writeDotenv(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		files, signatures, fingerprints []string
+		skipped, failed                 []string
+	)
+
+	for _, entry := range r.Signatures {
+		files = append(files, entry.File)
+		signatures = append(signatures, entry.Signature)
+		fingerprints = append(fingerprints, entry.Fingerprint)
+	}
+
+	for _, entry := range r.Skipped {
+		skipped = append(skipped, entry.File)
+	}
+
+	for _, entry := range r.Failed {
+		failed = append(failed, entry.File)
+	}
+
+	values := [][2]string{
+		{"GPGSIGN_MODE", r.Mode},
+		{"GPGSIGN_SIGNATURE_COUNT", strconv.Itoa(len(r.Signatures))},
+		{"GPGSIGN_FILES", strings.Join(plugin_slice.Unique(files), ",")},
+		{"GPGSIGN_SIGNATURES", strings.Join(plugin_slice.Unique(signatures), ",")},
+		{"GPGSIGN_FINGERPRINTS", strings.Join(plugin_slice.Unique(fingerprints), ",")},
+		{"GPGSIGN_SKIPPED_COUNT", strconv.Itoa(len(r.Skipped))},
+		{"GPGSIGN_SKIPPED", strings.Join(skipped, ",")},
+		{"GPGSIGN_FAILED_COUNT", strconv.Itoa(len(r.Failed))},
+		{"GPGSIGN_FAILED", strings.Join(failed, ",")},
+	}
+
+	var content strings.Builder
+
+	for _, value := range values {
+		fmt.Fprintf(&content, "%s=%s\n", value[0], strconv.Quote(value[1]))
+	}
+
+	return writeReportFile(path, []byte(content.String()))
+}
+
+// writeReport writes the report files configured in the plugin settings.
+func (p *Plugin) writeReport() error {
+	report := p.Settings.report
+	if report == nil {
+		return nil
+	}
+
+	if p.Settings.ReportFile != "" {
+		log.Info().Str("file", p.Settings.ReportFile).Msg("write signing report")
+
+		if err := report.writeJSON(p.Settings.ReportFile); err != nil {
+			return err
+		}
+	}
+
+	if p.Settings.ReportDotenv != "" {
+		log.Info().Str("file", p.Settings.ReportDotenv).Msg("write signing report dotenv")
+
+		if err := report.writeDotenv(p.Settings.ReportDotenv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withoutExcludes returns the given files without the excluded files, which are added
+// to the report.
+func (p *Plugin) withoutExcludes(files []string) []string {
+	for _, path := range files {
+		if slices.Contains(p.Settings.excludes, path) {
+			p.Settings.report.addSkipped(path, SkipReasonExcluded)
+		}
+	}
+
+	return plugin_slice.SetDifference(files, p.Settings.excludes, true)
+}
+
+func writeReportFile(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+			return fmt.Errorf("failed to create report dir: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, data, reportFilePerm); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
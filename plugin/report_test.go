@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+func TestPlugin_SignReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.zip")
+	excluded := filepath.Join(tmpDir, "app.txt")
+
+	require.NoError(t, os.WriteFile(app, []byte("hello\n"), 0o600))
+	require.NoError(t, os.WriteFile(excluded, []byte("world\n"), 0o600))
+
+	gpgclient := newTestClient(t)
+	gpgclient.SignatureTime = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	p := &Plugin{
+		Settings: &Settings{
+			DetachSign:   true,
+			Armor:        true,
+			ReportFile:   filepath.Join(tmpDir, "report", "gpgsign.json"),
+			ReportDotenv: filepath.Join(tmpDir, "gpgsign.env"),
+			files:        []string{app, excluded},
+			excludes:     []string{excluded},
+			report:       newReport(ModeSign, gnupg.BackendNative),
+		},
+	}
+
+	require.NoError(t, p.sign(t.Context(), gpgclient))
+	require.NoError(t, p.writeReport())
+
+	data, err := os.ReadFile(p.Settings.ReportFile)
+	require.NoError(t, err)
+
+	var report Report
+
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, ModeSign, report.Mode)
+	assert.Equal(t, gnupg.BackendNative, report.Backend)
+	assert.Equal(t, []ReportSignature{
+		{
+			File:        app,
+			Size:        6,
+			SHA256:      "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+			Signature:   app + ".asc",
+			Type:        gnupg.SignatureDetached,
+			Armor:       true,
+			Fingerprint: gpgclient.Key.Fingerprint,
+			KeyID:       keyID(gpgclient.Key),
+			Timestamp:   gpgclient.SignatureTime,
+		},
+	}, report.Signatures)
+	assert.Equal(t, []ReportSkipped{{File: excluded, Reason: SkipReasonExcluded}}, report.Skipped)
+
+	dotenv, err := os.ReadFile(p.Settings.ReportDotenv)
+	require.NoError(t, err)
+	assert.Equal(t, "GPGSIGN_MODE=\"sign\"\n"+
+		"GPGSIGN_SIGNATURE_COUNT=\"1\"\n"+
+		"GPGSIGN_FILES=\""+app+"\"\n"+
+		"GPGSIGN_SIGNATURES=\""+app+".asc\"\n"+
+		"GPGSIGN_FINGERPRINTS=\""+gpgclient.Key.Fingerprint+"\"\n"+
+		"GPGSIGN_SKIPPED_COUNT=\"1\"\n"+
+		"GPGSIGN_SKIPPED=\""+excluded+"\"\n"+
+		"GPGSIGN_FAILED_COUNT=\"0\"\n"+
+		"GPGSIGN_FAILED=\"\"\n", string(dotenv))
+}
+
+func TestPlugin_SignReportTimestamp(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.zip")
+
+	require.NoError(t, os.WriteFile(app, []byte("hello\n"), 0o600))
+
+	p := &Plugin{
+		Settings: &Settings{
+			DetachSign: true,
+			files:      []string{app},
+			report:     newReport(ModeSign, gnupg.BackendNative),
+		},
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, p.sign(t.Context(), newTestClient(t)))
+
+	// Without pinned time, the creation time of the signature is recorded
+	require.Len(t, p.Settings.report.Signatures, 1)
+
+	sig, err := os.ReadFile(app + ".sig")
+	require.NoError(t, err)
+
+	pkt, err := packet.Read(bytes.NewReader(sig))
+	require.NoError(t, err)
+
+	signature, ok := pkt.(*packet.Signature)
+	require.True(t, ok)
+
+	got := p.Settings.report.Signatures[0].Timestamp
+	assert.Equal(t, signature.CreationTime.UTC(), got)
+	assert.False(t, got.Before(before))
+}
+
+func TestPlugin_SignReportFailed(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.zip")
+	missing := filepath.Join(tmpDir, "missing.zip")
+
+	require.NoError(t, os.WriteFile(app, []byte("hello\n"), 0o600))
+
+	p := &Plugin{
+		Settings: &Settings{
+			Mode:       ModeSign,
+			Backend:    gnupg.BackendNative,
+			DetachSign: true,
+			ReportFile: filepath.Join(tmpDir, "gpgsign.json"),
+			files:      []string{app, missing},
+			report:     newReport(ModeSign, gnupg.BackendNative),
+		},
+	}
+
+	signErr := p.sign(t.Context(), newTestClient(t))
+	require.ErrorIs(t, signErr, ErrSignFilesFailed)
+
+	p.Settings.report.setError(signErr)
+	require.NoError(t, p.writeReport())
+
+	data, err := os.ReadFile(p.Settings.ReportFile)
+	require.NoError(t, err)
+
+	var report Report
+
+	require.NoError(t, json.Unmarshal(data, &report))
+	require.Len(t, report.Signatures, 1)
+	assert.Equal(t, app, report.Signatures[0].File)
+	require.Len(t, report.Failed, 1)
+	assert.Equal(t, missing, report.Failed[0].File)
+	assert.Contains(t, report.Failed[0].Error, "no such file or directory")
+	assert.Equal(t, signErr.Error(), report.Error)
+}
+
+func TestPlugin_SignReportChecksumOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	app := filepath.Join(tmpDir, "app.zip")
+	sums := filepath.Join(tmpDir, "SHA256SUMS")
+
+	require.NoError(t, os.WriteFile(app, []byte("hello\n"), 0o600))
+	require.NoError(t, os.WriteFile(sums, []byte("outdated\n"), 0o600))
+
+	p := &Plugin{
+		Settings: &Settings{
+			DetachSign:   true,
+			Checksums:    []string{"sha256"},
+			ChecksumFile: sums,
+			ChecksumOnly: true,
+			files:        []string{app, sums},
+			report:       newReport(ModeSign, gnupg.BackendNative),
+		},
+	}
+
+	require.NoError(t, p.sign(t.Context(), newTestClient(t)))
+
+	report := p.Settings.report
+
+	require.Len(t, report.Signatures, 1)
+	assert.Equal(t, sums, report.Signatures[0].File)
+	assert.Equal(t, sums+".sig", report.Signatures[0].Signature)
+	assert.False(t, report.Signatures[0].Armor)
+	assert.Equal(t, []ReportSkipped{
+		{File: sums, Reason: SkipReasonChecksumFile},
+		{File: app, Reason: SkipReasonChecksumOnly},
+	}, report.Skipped)
+}
+
+func TestReport_UpdateSignatures(t *testing.T) {
+	report := &Report{
+		Signatures: []ReportSignature{
+			{File: "a.txt", Signature: "a.txt.sig"},
+			{File: "b.txt", Signature: "b.txt.sig"},
+		},
+	}
+
+	err := report.updateSignatures([]string{"b.txt.sig"}, func(entry *ReportSignature) error {
+		entry.Signature = "b.txt.asc"
+		entry.Armor = true
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []ReportSignature{
+		{File: "a.txt", Signature: "a.txt.sig"},
+		{File: "b.txt", Signature: "b.txt.asc", Armor: true},
+	}, report.Signatures)
+
+	var disabled *Report
+
+	assert.NoError(t, disabled.updateSignatures([]string{"a.txt.sig"}, nil))
+	assert.NoError(t, disabled.addSignature(nil, gnupg.SignatureCreated{}, false, true, false, "a.txt", "a.txt.sig"))
+	disabled.addSkipped("a.txt", SkipReasonExcluded)
+	disabled.addFailed("a.txt", os.ErrNotExist)
+	disabled.setError(os.ErrNotExist)
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var (
+	ErrNoRepomdFiles           = errors.New("no repomd.xml files found")
+	ErrUnsupportedKeyAlgorithm = errors.New("unsupported key algorithm")
+	ErrRepoGPGKeyMismatch      = errors.New("gpgkey of repo file does not point to the exported key")
+)
+
+const (
+	rpmRepomdFile = "repomd.xml"
+	rpmRepoExt    = ".repo"
+	rpmGPGKey     = "gpgkey"
+)
+
+// signRpm signs all `repomd.xml` files found below the repository root with an
+// armored detached `repomd.xml.asc` signature. The `gpgkey` entries of the `.repo` files
+// below the repository root must point to the exported public key.
+func (p *Plugin) signRpm(ctx context.Context, gpgclient *gnupg.Client) error {
+	algo, err := gpgclient.SigningKeyAlgorithm()
+	if err != nil {
+		return err
+	}
+
+	// Public key algorithms accepted by rpm and dnf to verify repository metadata
+	accepted := []packet.PublicKeyAlgorithm{
+		packet.PubKeyAlgoRSA,
+		packet.PubKeyAlgoRSASignOnly,
+		packet.PubKeyAlgoDSA,
+		packet.PubKeyAlgoECDSA,
+		packet.PubKeyAlgoEdDSA,
+	}
+
+	if !slices.Contains(accepted, algo) {
+		return fmt.Errorf("%w: %s is not accepted by dnf", ErrUnsupportedKeyAlgorithm, gnupg.AlgorithmName(algo))
+	}
+
+	repomds, err := findFiles(p.Settings.RepositoryRoot, rpmRepomdFile)
+	if err != nil {
+		return fmt.Errorf("failed to find repomd.xml files: %w", err)
+	}
+
+	repomds = p.withoutExcludes(repomds)
+	if len(repomds) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoRepomdFiles, p.Settings.RepositoryRoot)
+	}
+
+	if err := p.validateRepoFiles(); err != nil {
+		return err
+	}
+
+	requests := make([]gnupg.SignRequest, 0, len(repomds))
+
+	for _, path := range repomds {
+		log.Info().Str("file", path).Msg("sign rpm repository metadata")
+
+		requests = append(requests, gnupg.SignRequest{
+			Path:       path,
+			Output:     gnupg.SignaturePath(true, true, false, path),
+			Armor:      true,
+			DetachSign: true,
+		})
+	}
+
+	if err := p.signBatch(ctx, gpgclient, requests); err != nil {
+		return err
+	}
+
+	return p.exportPublicKey(gpgclient)
+}
+
+// validateRepoFiles checks that every `gpgkey` entry of the `.repo` files below the
+// repository root points to the exported public key, i.e. at least one of its URLs ends
+// with the export path. Without an exported key, the entries cannot be validated.
+func (p *Plugin) validateRepoFiles() error {
+	repos, err := findFilesFunc(p.Settings.RepositoryRoot, func(name string) bool {
+		return strings.HasSuffix(name, rpmRepoExt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find repo files: %w", err)
+	}
+
+	for _, path := range repos {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read repo file: %w", err)
+		}
+
+		entries := repoGPGKeys(string(data))
+
+		if p.Settings.ExportKey == "" {
+			if len(entries) > 0 {
+				log.Warn().Str("file", path).Msg("gpgkey not validated: no public key exported")
+			}
+
+			continue
+		}
+
+		exportKey := "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p.Settings.ExportKey)), "/")
+
+		for _, urls := range entries {
+			if !slices.ContainsFunc(urls, func(value string) bool {
+				return strings.HasSuffix(repoURLPath(value), exportKey)
+			}) {
+				return fmt.Errorf("%w: %s: %s=%s", ErrRepoGPGKeyMismatch, path, rpmGPGKey, strings.Join(urls, " "))
+			}
+		}
+
+		log.Debug().Str("file", path).Int("entries", len(entries)).Msg("validate repo file gpgkey")
+	}
+
+	return nil
+}
+
+// repoGPGKeys returns the URLs of every `gpgkey` entry of the given repo file. The URLs
+// of an entry are separated by whitespace or commas and may continue on indented lines.
+func repoGPGKeys(content string) [][]string {
+	var (
+		entries [][]string
+		current []string
+		inEntry bool
+	)
+
+	split := func(value string) []string {
+		return strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	}
+
+	for line := range strings.Lines(content) {
+		if inEntry && line != strings.TrimLeft(line, " \t") && strings.TrimSpace(line) != "" {
+			current = append(current, split(line)...)
+
+			continue
+		}
+
+		if inEntry {
+			entries = append(entries, current)
+			inEntry = false
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != rpmGPGKey {
+			continue
+		}
+
+		current = split(value)
+		inEntry = true
+	}
+
+	if inEntry {
+		entries = append(entries, current)
+	}
+
+	return entries
+}
+
+// repoURLPath returns the path of the given URL, or the value itself if it is not a URL.
+func repoURLPath(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return "/" + strings.TrimPrefix(value, "/")
+	}
+
+	return u.Path
+}
+
+// findFiles returns all regular files with the given name below the root directory.
+func findFiles(root, name string) ([]string, error) {
+	return findFilesFunc(root, func(n string) bool { return n == name })
+}
+
+// findFilesFunc returns all regular files below the root directory whose name matches.
+func findFilesFunc(root string, match func(name string) bool) ([]string, error) {
+	result := make([]string, 0)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() && match(d.Name()) {
+			result = append(result, path)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_SignRpm(t *testing.T) {
+	tests := []struct {
+		name      string
+		repos     []string
+		exportKey string
+		repoFile  string
+		wantErr   error
+	}{
+		{
+			name:  "single repository",
+			repos: []string{"x86_64"},
+		},
+		{
+			name:      "nested repositories with public key",
+			repos:     []string{"el9/x86_64", "el9/aarch64"},
+			exportKey: "RPM-GPG-KEY",
+		},
+		{
+			name:      "repo file pointing to public key",
+			repos:     []string{"x86_64"},
+			exportKey: "RPM-GPG-KEY",
+			repoFile:  "[demo]\nbaseurl=https://rpm.example.com/$basearch\ngpgkey=https://rpm.example.com/keys/RPM-GPG-KEY\n",
+		},
+		{
+			name:      "repo file pointing to other key",
+			repos:     []string{"x86_64"},
+			exportKey: "RPM-GPG-KEY",
+			repoFile:  "[demo]\ngpgkey=https://rpm.example.com/RPM-GPG-KEY-old\n",
+			wantErr:   ErrRepoGPGKeyMismatch,
+		},
+		{
+			name:     "repo file without exported key",
+			repos:    []string{"x86_64"},
+			repoFile: "[demo]\ngpgkey=https://rpm.example.com/RPM-GPG-KEY\n",
+		},
+		{
+			name:    "no repomd files",
+			wantErr: ErrNoRepomdFiles,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			for _, repo := range tt.repos {
+				dir := filepath.Join(root, repo, "repodata")
+				require.NoError(t, os.MkdirAll(dir, 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "repomd.xml"), []byte("<repomd/>\n"), 0o600))
+			}
+
+			if tt.repoFile != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(root, "demo.repo"), []byte(tt.repoFile), 0o600))
+			}
+
+			p := &Plugin{
+				Settings: &Settings{
+					RepositoryRoot:   root,
+					ExportKey:        tt.exportKey,
+					VerifySignatures: true,
+				},
+			}
+
+			err := p.signRpm(t.Context(), newTestClient(t))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for _, repo := range tt.repos {
+				sig, err := os.ReadFile(filepath.Join(root, repo, "repodata", "repomd.xml.asc"))
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(string(sig), "-----BEGIN PGP SIGNATURE-----"))
+			}
+
+			if tt.exportKey != "" {
+				assert.FileExists(t, filepath.Join(root, tt.exportKey))
+			}
+		})
+	}
+}
+
+func TestRepoGPGKeys(t *testing.T) {
+	content := `[demo]
+name=Demo
+gpgkey=https://rpm.example.com/RPM-GPG-KEY,file:///etc/pki/rpm-gpg/RPM-GPG-KEY-demo
+gpgcheck=1
+
+[demo-source]
+gpgkey=https://rpm.example.com/RPM-GPG-KEY-old
+  https://rpm.example.com/RPM-GPG-KEY
+`
+
+	assert.Equal(t, [][]string{
+		{"https://rpm.example.com/RPM-GPG-KEY", "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-demo"},
+		{"https://rpm.example.com/RPM-GPG-KEY-old", "https://rpm.example.com/RPM-GPG-KEY"},
+	}, repoGPGKeys(content))
+}
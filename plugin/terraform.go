@@ -0,0 +1,130 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+)
+
+var (
+	ErrNoProviderArchives      = errors.New("no terraform provider archives found")
+	ErrInvalidProviderArchive  = errors.New("invalid terraform provider archive name")
+	ErrProviderArchiveMismatch = errors.New("terraform provider archives do not belong to the same release")
+)
+
+const (
+	terraformManifestFile = "terraform-registry-manifest.json"
+	terraformManifestPerm = 0o644
+	// terraformDefaultManifest is used if no registry manifest exists. It declares
+	// the plugin protocol version 5.0 that the registry assumes by default.
+	terraformDefaultManifest = `{"version":1,"metadata":{"protocol_versions":["5.0"]}}` + "\n"
+)
+
+// terraformArchiveRegex matches registry compliant provider archive names in the
+// format `terraform-provider-<name>_<version>_<os>_<arch>.zip`.
+var terraformArchiveRegex = regexp.MustCompile(`^(terraform-provider-[^_]+_[^_]+)_[^_]+_[^_]+\.zip$`)
+
+// signTerraform creates the `<prefix>_SHA256SUMS` file for the provider archives in the
+// given files, including the `<prefix>_manifest.json` registry manifest, and signs it
+// with a binary detached `<prefix>_SHA256SUMS.sig` signature.
+func (p *Plugin) signTerraform(ctx context.Context, gpgclient *gnupg.Client) error {
+	archives := make([]string, 0)
+
+	for _, path := range p.withoutExcludes(p.Settings.files) {
+		if filepath.Ext(path) != ".zip" {
+			p.Settings.report.addSkipped(path, SkipReasonUnsupported)
+
+			continue
+		}
+
+		archives = append(archives, path)
+	}
+
+	if len(archives) == 0 {
+		return ErrNoProviderArchives
+	}
+
+	prefix, err := terraformReleasePrefix(archives)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(archives[0])
+
+	manifest := filepath.Join(dir, prefix+"_manifest.json")
+	if err := writeTerraformManifest(terraformManifestFile, manifest); err != nil {
+		return err
+	}
+
+	files := append(slices.Clone(archives), manifest)
+	slices.SortFunc(files, func(a, b string) int {
+		return strings.Compare(filepath.Base(a), filepath.Base(b))
+	})
+
+	sums := filepath.Join(dir, prefix+"_SHA256SUMS")
+
+	log.Info().Str("file", sums).Msg("create terraform provider checksum file")
+
+	if err := writeChecksumFile(sums, "sha256", files); err != nil {
+		return err
+	}
+
+	return p.signFile(ctx, gpgclient, false, true, false, sums)
+}
+
+// terraformReleasePrefix returns the common `terraform-provider-<name>_<version>` prefix
+// of the given provider archives. All archives must be located in the same directory.
+func terraformReleasePrefix(archives []string) (string, error) {
+	var prefix, dir string
+
+	for _, path := range archives {
+		match := terraformArchiveRegex.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidProviderArchive, path)
+		}
+
+		if prefix == "" {
+			prefix, dir = match[1], filepath.Dir(path)
+
+			continue
+		}
+
+		if match[1] != prefix || filepath.Dir(path) != dir {
+			return "", fmt.Errorf("%w: %s", ErrProviderArchiveMismatch, path)
+		}
+	}
+
+	return prefix, nil
+}
+
+// writeTerraformManifest copies the registry manifest from src to dst. If src does
+// not exist, a default manifest is written instead.
+func writeTerraformManifest(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info().Msgf("%s not found: use default registry manifest", src)
+
+		content = []byte(terraformDefaultManifest)
+	} else if err != nil {
+		return fmt.Errorf("failed to read registry manifest: %w", err)
+	}
+
+	if err := os.WriteFile(dst, content, terraformManifestPerm); err != nil {
+		return fmt.Errorf("failed to write registry manifest %s: %w", dst, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformReleasePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		archives []string
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "single release",
+			archives: []string{
+				"dist/terraform-provider-demo_1.2.3_linux_amd64.zip",
+				"dist/terraform-provider-demo_1.2.3_darwin_arm64.zip",
+			},
+			want: "terraform-provider-demo_1.2.3",
+		},
+		{
+			name:     "invalid name",
+			archives: []string{"dist/demo_1.2.3_linux_amd64.zip"},
+			wantErr:  ErrInvalidProviderArchive,
+		},
+		{
+			name: "mixed versions",
+			archives: []string{
+				"dist/terraform-provider-demo_1.2.3_linux_amd64.zip",
+				"dist/terraform-provider-demo_1.2.4_linux_amd64.zip",
+			},
+			wantErr: ErrProviderArchiveMismatch,
+		},
+		{
+			name: "mixed directories",
+			archives: []string{
+				"dist/terraform-provider-demo_1.2.3_linux_amd64.zip",
+				"other/terraform-provider-demo_1.2.3_linux_arm64.zip",
+			},
+			wantErr: ErrProviderArchiveMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := terraformReleasePrefix(tt.archives)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlugin_SignTerraform(t *testing.T) {
+	tests := []struct {
+		name         string
+		manifest     string
+		wantManifest string
+	}{
+		{
+			name:         "default manifest",
+			wantManifest: terraformDefaultManifest,
+		},
+		{
+			name:         "registry manifest",
+			manifest:     `{"version":1,"metadata":{"protocol_versions":["6.0"]}}`,
+			wantManifest: `{"version":1,"metadata":{"protocol_versions":["6.0"]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			require.NoError(t, os.Mkdir("dist", 0o755))
+
+			if tt.manifest != "" {
+				require.NoError(t, os.WriteFile(terraformManifestFile, []byte(tt.manifest), 0o600))
+			}
+
+			files := []string{
+				filepath.Join("dist", "terraform-provider-demo_1.2.3_linux_amd64.zip"),
+				filepath.Join("dist", "terraform-provider-demo_1.2.3_darwin_arm64.zip"),
+			}
+
+			for _, file := range files {
+				require.NoError(t, os.WriteFile(file, []byte(file), 0o600))
+			}
+
+			p := &Plugin{
+				Settings: &Settings{
+					VerifySignatures: true,
+					files:            files,
+				},
+			}
+
+			require.NoError(t, p.signTerraform(t.Context(), newTestClient(t)))
+
+			manifest, err := os.ReadFile(filepath.Join("dist", "terraform-provider-demo_1.2.3_manifest.json"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantManifest, string(manifest))
+
+			sums, err := os.ReadFile(filepath.Join("dist", "terraform-provider-demo_1.2.3_SHA256SUMS"))
+			require.NoError(t, err)
+
+			names := make([]string, 0)
+			for _, line := range strings.Split(strings.TrimSpace(string(sums)), "\n") {
+				names = append(names, strings.Fields(line)[1])
+			}
+
+			assert.Equal(t, []string{
+				"terraform-provider-demo_1.2.3_darwin_arm64.zip",
+				"terraform-provider-demo_1.2.3_linux_amd64.zip",
+				"terraform-provider-demo_1.2.3_manifest.json",
+			}, names)
+
+			sig, err := os.ReadFile(filepath.Join("dist", "terraform-provider-demo_1.2.3_SHA256SUMS.sig"))
+			require.NoError(t, err)
+			assert.False(t, strings.HasPrefix(string(sig), "-----BEGIN"))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidSignatureTime = errors.New("invalid signature time")
+
+const (
+	SignatureTimeSourceDateEpoch = "source-date-epoch"
+	SignatureTimeCommit          = "commit"
+
+	sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+)
+
+// validateSignatureTime checks that the signature time setting is either a supported
+// keyword or an RFC3339 timestamp.
+func (p *Plugin) validateSignatureTime() error {
+	switch p.Settings.SignatureTime {
+	case "", SignatureTimeSourceDateEpoch, SignatureTimeCommit:
+		return nil
+	}
+
+	if _, err := time.Parse(time.RFC3339, p.Settings.SignatureTime); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignatureTime, err)
+	}
+
+	return nil
+}
+
+// signatureTime resolves the signature time setting. It returns the zero time if the
+// signature time is not pinned.
+func (p *Plugin) signatureTime(ctx context.Context) (time.Time, error) {
+	var (
+		epoch string
+		err   error
+	)
+
+	switch p.Settings.SignatureTime {
+	case "":
+		return time.Time{}, nil
+	case SignatureTimeSourceDateEpoch:
+		epoch = os.Getenv(sourceDateEpochEnv)
+		if epoch == "" {
+			return time.Time{}, fmt.Errorf("%w: %s is not set", ErrInvalidSignatureTime, sourceDateEpochEnv)
+		}
+	case SignatureTimeCommit:
+		epoch, err = p.runGit(ctx, nil, "log", "-1", "--format=%ct", p.Settings.GitCommit)
+		if err != nil {
+			return time.Time{}, err
+		}
+	default:
+		ts, err := time.Parse(time.RFC3339, p.Settings.SignatureTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidSignatureTime, err)
+		}
+
+		return ts.UTC(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidSignatureTime, err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
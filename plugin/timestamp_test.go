@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_SignatureTime(t *testing.T) {
+	tests := []struct {
+		name          string
+		signatureTime string
+		epoch         string
+		want          time.Time
+		wantErr       error
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name:          "rfc3339",
+			signatureTime: "2024-01-01T01:00:00+01:00",
+			want:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "source date epoch",
+			signatureTime: SignatureTimeSourceDateEpoch,
+			epoch:         "1704067200",
+			want:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "source date epoch not set",
+			signatureTime: SignatureTimeSourceDateEpoch,
+			wantErr:       ErrInvalidSignatureTime,
+		},
+		{
+			name:          "invalid source date epoch",
+			signatureTime: SignatureTimeSourceDateEpoch,
+			epoch:         "yesterday",
+			wantErr:       ErrInvalidSignatureTime,
+		},
+		{
+			name:          "invalid timestamp",
+			signatureTime: "2024-01-01",
+			wantErr:       ErrInvalidSignatureTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(sourceDateEpochEnv, tt.epoch)
+
+			p := &Plugin{Settings: &Settings{SignatureTime: tt.signatureTime}}
+
+			got, err := p.signatureTime(t.Context())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NoError(t, p.validateSignatureTime())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlugin_SignatureTimeCommit(t *testing.T) {
+	if _, err := exec.LookPath(gitBin); err != nil {
+		t.Skip("git not installed")
+	}
+
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_COMMITTER_DATE", "2024-01-01T00:00:00Z")
+
+	require.NoError(t, exec.Command(gitBin, "init", "--quiet").Run())
+	require.NoError(t, exec.Command(
+		gitBin, "-c", "user.name=John Doe", "-c", "user.email=john.doe@example.com",
+		"commit", "--quiet", "--allow-empty", "-m", "init",
+	).Run())
+
+	p := &Plugin{Settings: &Settings{SignatureTime: SignatureTimeCommit, GitCommit: "HEAD"}}
+
+	got, err := p.signatureTime(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), got)
+}
+
+func TestPlugin_SignReproducible(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	gpgclient := newTestGPGClient(t)
+	gpgclient.SignatureTime = gpgclient.Key.CreationTime.Add(time.Hour)
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+
+	var sigs []string
+
+	for _, output := range []string{file + ".1.asc", file + ".2.asc"} {
+		_, err := gpgclient.SignFileTo(t.Context(), true, true, false, file, output)
+		require.NoError(t, err)
+
+		sig, err := os.ReadFile(output)
+		require.NoError(t, err)
+
+		sigs = append(sigs, string(sig))
+	}
+
+	assert.Equal(t, sigs[0], sigs[1])
+
+	block, err := armor.Decode(strings.NewReader(sigs[0]))
+	require.NoError(t, err)
+
+	p, err := packet.Read(block.Body)
+	require.NoError(t, err)
+
+	sig, ok := p.(*packet.Signature)
+	require.True(t, ok)
+	assert.Equal(t, gpgclient.SignatureTime, sig.CreationTime.UTC())
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2024, Robert Kaussow <mail@thegeeklab.de>
+
+// Use of this source code is governed by an Apache 2.0 license that can be
+// found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-gpgsign/gnupg"
+	plugin_slice "github.com/thegeeklab/wp-plugin-go/v6/slice"
+)
+
+var (
+	ErrNoFilesToVerify    = errors.New("no files found to verify")
+	ErrVerificationFailed = errors.New("signature verification failed")
+)
+
+// verify checks the signatures of all given files against the configured public keys
+// and prints a report. It returns an error if any of the signatures is not valid.
+func (p *Plugin) verify(gpgclient *gnupg.Client) error {
+	if err := gpgclient.ReadPublicKeys(p.Settings.PublicKey); err != nil {
+		return err
+	}
+
+	fmt.Print(
+		"GPG public keys\n",
+		fmt.Sprintf("Fingerprints : %s\n", strings.Join(gpgclient.PublicKeys(), ", ")),
+		"\n",
+	)
+
+	files := plugin_slice.SetDifference(p.Settings.files, p.Settings.excludes, true)
+	if len(files) == 0 {
+		return ErrNoFilesToVerify
+	}
+
+	log.Info().Msg("verify files")
+
+	seen := make(map[string]bool)
+	results := make([]*gnupg.Verification, 0, len(files))
+
+	for _, path := range files {
+		res := gpgclient.VerifyFile(path, p.Settings.VerifyFingerprints)
+
+		// A file and its detached signature may both be matched.
+		if seen[res.Path] {
+			continue
+		}
+
+		seen[res.Path] = true
+
+		results = append(results, res)
+	}
+
+	failed := 0
+
+	fmt.Print("Verification report\n")
+
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+
+			fmt.Printf("FAILED : %s: %s\n", res.Path, res.Err)
+
+			continue
+		}
+
+		fmt.Printf(
+			"OK     : %s (%s signature %s by %s at %s)\n",
+			res.Path, res.Type, res.SignaturePath, res.Fingerprint, res.CreationTime,
+		)
+	}
+
+	fmt.Print("\n")
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d files", ErrVerificationFailed, failed, len(results))
+	}
+
+	return nil
+}